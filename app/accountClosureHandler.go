@@ -0,0 +1,107 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-auth/service"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net/http"
+)
+
+type AccountClosureHandler struct { //REST handler (adapter)
+	service service.AccountClosureService
+}
+
+func (h AccountClosureHandler) CloseAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var request dto.AccountClosureRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of account closure request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	if appErr := request.Validate(); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	response, appErr := h.service.CloseAccount(request, getIdentity(r))
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, response)
+}
+
+func (h AccountClosureHandler) GetPendingClosuresHandler(w http.ResponseWriter, r *http.Request) {
+	response, appErr := h.service.GetPendingClosures()
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, response)
+}
+
+func (h AccountClosureHandler) EraseCustomerDataHandler(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeCustomerActionRequest(w, r, false)
+	if !ok {
+		return
+	}
+
+	response, appErr := h.service.EraseCustomerData(request, getIdentity(r).Username)
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, response)
+}
+
+func (h AccountClosureHandler) PlaceRetentionHoldHandler(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeCustomerActionRequest(w, r, true)
+	if !ok {
+		return
+	}
+
+	if appErr := h.service.PlaceRetentionHold(request, getIdentity(r).Username); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, errs.NewMessageObject(""))
+}
+
+func (h AccountClosureHandler) ReleaseRetentionHoldHandler(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeCustomerActionRequest(w, r, false)
+	if !ok {
+		return
+	}
+
+	if appErr := h.service.ReleaseRetentionHold(request, getIdentity(r).Username); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, errs.NewMessageObject(""))
+}
+
+// decodeCustomerActionRequest decodes and validates the json body of an admin's action on the data of a customer,
+// writing the error response if it is invalid.
+func decodeCustomerActionRequest(w http.ResponseWriter, r *http.Request, isReasonRequired bool) (dto.CustomerActionRequest, bool) {
+	var request dto.CustomerActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of customer action request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return request, false
+	}
+
+	if appErr := request.Validate(isReasonRequired); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return request, false
+	}
+
+	return request, true
+}
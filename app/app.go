@@ -11,6 +11,8 @@ import (
 	"github.com/joho/godotenv"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -24,10 +26,6 @@ func checkEnvVars() {
 		"SERVER_ADDRESS",
 		"SERVER_PORT",
 		"SERVER_DOMAIN",
-		"MAIL_SERVER_ADDRESS",
-		"MAIL_SERVER_PORT",
-		"MAIL_SERVER_USER",
-		"MAIL_SERVER_PASSWORD",
 		"MAIL_SENDER",
 		"FRONTEND_SERVER_ADDRESS",
 		"FRONTEND_SERVER_DOMAIN",
@@ -48,11 +46,83 @@ func checkEnvVars() {
 		envVars = append(envVars, "FRONTEND_SERVER_PORT")
 	}
 
+	switch os.Getenv("MAIL_TRANSPORT") {
+	case "", domain.EmailTransportSmtp:
+		envVars = append(envVars, "MAIL_SERVER_ADDRESS", "MAIL_SERVER_PORT")
+		auth := os.Getenv("MAIL_SMTP_AUTH")
+		if auth == "" && val == "production" {
+			auth = domain.SmtpAuthPlain
+		}
+		if auth != "" && auth != domain.SmtpAuthNone {
+			envVars = append(envVars, "MAIL_SERVER_USER", "MAIL_SERVER_PASSWORD")
+		}
+	case domain.EmailTransportFile:
+	case domain.EmailTransportHttp:
+		envVars = append(envVars, "MAIL_HTTP_URL")
+	default:
+		logger.Fatal("Environment variable MAIL_TRANSPORT is not one of smtp, file or http")
+	}
+
+	if os.Getenv("DKIM_PRIVATE_KEY_PATH") != "" {
+		envVars = append(envVars, "DKIM_SELECTOR")
+	}
+
 	for _, key := range envVars {
 		if os.Getenv(key) == "" {
 			logger.Fatal(fmt.Sprintf("Environment variable %s was not defined", key))
 		}
 	}
+
+	optionalDurationEnvVars := []string{
+		"REGISTRATION_EXPIRY",
+		"ERASURE_RETENTION_PERIOD",
+	}
+
+	for _, key := range optionalDurationEnvVars {
+		if val := os.Getenv(key); val != "" {
+			if d, err := time.ParseDuration(val); err != nil || d <= 0 {
+				logger.Fatal(fmt.Sprintf("Environment variable %s is not a valid positive duration", key))
+			}
+		}
+	}
+
+	optionalEnumEnvVars := map[string][]string{
+		"MAIL_SMTP_SECURITY":    {domain.SmtpSecurityNone, domain.SmtpSecurityStartTls, domain.SmtpSecurityTls},
+		"MAIL_SMTP_AUTH":        {domain.SmtpAuthNone, domain.SmtpAuthPlain, domain.SmtpAuthLogin, domain.SmtpAuthCramMd5},
+		"DKIM_CANONICALIZATION": {"simple/simple", "simple/relaxed", "relaxed/simple", "relaxed/relaxed"},
+		"RATE_LIMIT_STORE":      {domain.VisitorStoreMemory, domain.VisitorStoreDb},
+	}
+
+	for key, options := range optionalEnumEnvVars {
+		val := os.Getenv(key)
+		if val == "" {
+			continue
+		}
+		isValid := false
+		for _, option := range options {
+			isValid = isValid || val == option
+		}
+		if !isValid {
+			logger.Fatal(fmt.Sprintf("Environment variable %s is not one of %s", key, strings.Join(options, ", ")))
+		}
+	}
+
+	optionalBoolEnvVars := []string{
+		"REGISTRATION_APPROVAL_REQUIRED",
+		"KYC_REQUIRED",
+	}
+
+	for _, key := range optionalBoolEnvVars {
+		if val := os.Getenv(key); val != "" {
+			if _, err := strconv.ParseBool(val); err != nil {
+				logger.Fatal(fmt.Sprintf("Environment variable %s is not a valid boolean", key))
+			}
+		}
+	}
+
+	if domain.IsKycRequired() && !domain.IsRegistrationApprovalRequired() {
+		logger.Fatal("Environment variable KYC_REQUIRED is true but REGISTRATION_APPROVAL_REQUIRED is not")
+	}
 }
 
 func Start() {
@@ -63,19 +133,35 @@ func Start() {
 	dbClient := getDbClient()
 	authRepositoryDb := domain.NewAuthRepositoryDb(dbClient)
 	registrationRepositoryDb := domain.NewRegistrationRepositoryDb(dbClient)
-	emailRepository := domain.NewDefaultEmailRepository()
+	go registrationRepositoryDb.Cleanup()
+	emailLogRepositoryDb := domain.NewEmailLogRepositoryDb(dbClient)
+	emailRepository := domain.NewDefaultEmailRepository(emailLogRepositoryDb)
+	emailOutboxService := service.NewDefaultEmailOutboxService(domain.NewEmailOutboxRepositoryDb(dbClient), emailRepository)
+	go emailOutboxService.Deliver()
+	oneTimeTokenRepositoryDb := domain.NewOneTimeTokenRepositoryDb(dbClient)
+	accountClosureRepositoryDb := domain.NewAccountClosureRepositoryDb(dbClient)
+	emailSuppressionRepositoryDb := domain.NewEmailSuppressionRepositoryDb(dbClient)
+
+	ipAccessRuleRepositoryDb := domain.NewIpAccessRuleRepositoryDb(dbClient)
+	ipAccessList := domain.NewIpAccessList(ipAccessRuleRepositoryDb)
+	go ipAccessList.ReloadPeriodically()
 
 	tokenRepository := domain.NewDefaultTokenRepository()
-	ah := AuthHandler{service.NewDefaultAuthService(
+	authService := service.NewDefaultAuthService(
 		authRepositoryDb,
 		registrationRepositoryDb,
 		domain.NewRolePermissions(),
 		tokenRepository,
-	)}
+		accountClosureRepositoryDb,
+		emailSuppressionRepositoryDb,
+		ipAccessList,
+	)
+	ah := AuthHandler{authService}
 	rh := RegistrationHandler{service.NewRegistrationService(
 		registrationRepositoryDb,
-		emailRepository,
 		tokenRepository,
+		oneTimeTokenRepositoryDb,
+		emailSuppressionRepositoryDb,
 	)}
 
 	router.
@@ -83,8 +169,14 @@ func Start() {
 		Methods(http.MethodPost, http.MethodOptions).
 		Name("Login")
 	router.HandleFunc("/auth/logout", ah.LogoutHandler).Methods(http.MethodPost, http.MethodOptions)
-	router.HandleFunc("/auth/verify", ah.VerifyHandler).Methods(http.MethodGet)
-	router.HandleFunc("/auth/refresh", ah.RefreshHandler).Methods(http.MethodPost, http.MethodOptions)
+	router.
+		HandleFunc("/auth/verify", ah.VerifyHandler).
+		Methods(http.MethodGet).
+		Name("Verify")
+	router.
+		HandleFunc("/auth/refresh", ah.RefreshHandler).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name("Refresh")
 	router.HandleFunc("/auth/continue", ah.ContinueHandler).Methods(http.MethodPost, http.MethodOptions)
 
 	router.
@@ -92,12 +184,183 @@ func Start() {
 		Methods(http.MethodPost, http.MethodOptions).
 		Name("Register")
 	router.HandleFunc("/auth/register/check", rh.CheckRegistrationHandler).Methods(http.MethodGet, http.MethodOptions)
-	router.HandleFunc("/auth/register/resend", rh.ResendHandler).Methods(http.MethodGet, http.MethodPost, http.MethodOptions)
+	router.
+		HandleFunc("/auth/register/resend", rh.ResendHandler).
+		Methods(http.MethodGet, http.MethodPost, http.MethodOptions).
+		Name("ResendLink")
 	router.HandleFunc("/auth/register/finish", rh.FinishRegistrationHandler).Methods(http.MethodPost, http.MethodOptions)
 
-	rmw := RateLimitingMiddleware{domain.NewDefaultVisitorRepository()}
+	fh := EmailFeedbackHandler{
+		service.NewDefaultEmailSuppressionService(emailSuppressionRepositoryDb),
+		os.Getenv("MAIL_FEEDBACK_SECRET"),
+	}
+	router.HandleFunc("/auth/emails/feedback", fh.ReportHandler).Methods(http.MethodPost)
+
+	uh := UsernameReminderHandler{service.NewDefaultUsernameReminderService(
+		domain.NewUsernameReminderRepositoryDb(dbClient),
+		emailSuppressionRepositoryDb,
+	)}
+	router.HandleFunc("/auth/username/forgot", uh.ForgotUsernameHandler).Methods(http.MethodPost, http.MethodOptions)
+
+	kycRepositoryDb := domain.NewKycRepositoryDb(dbClient)
+	blobRepository := domain.NewLocalBlobRepository()
+	kycService := service.NewDefaultKycService(
+		kycRepositoryDb,
+		registrationRepositoryDb,
+		blobRepository,
+		tokenRepository,
+	)
+	go kycService.Cleanup()
+	kh := KycHandler{kycService}
+
+	router.HandleFunc("/auth/register/kyc", kh.UploadDocumentHandler).Methods(http.MethodPost, http.MethodOptions)
+
+	amw := AuthenticationMiddleware{authService, ipAccessList}
+	customerRepositoryDb := domain.NewCustomerRepositoryDb(dbClient)
+	eh := EmailChangeHandler{service.NewDefaultEmailChangeService(
+		authRepositoryDb,
+		customerRepositoryDb,
+		registrationRepositoryDb,
+		tokenRepository,
+		oneTimeTokenRepositoryDb,
+	)}
+
+	router.
+		Handle("/auth/email/change", amw.AuthenticationHandler(http.HandlerFunc(eh.RequestEmailChangeHandler))).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name("EmailChange")
+	router.HandleFunc("/auth/email/change/confirm", eh.ConfirmEmailChangeHandler).Methods(http.MethodPost, http.MethodOptions)
+
+	ph := ProfileHandler{service.NewDefaultProfileService(customerRepositoryDb)}
+	router.
+		Handle("/auth/profile", amw.AuthenticationHandler(http.HandlerFunc(ph.GetProfileHandler))).
+		Methods(http.MethodGet)
+	router.
+		Handle("/auth/profile", amw.AuthenticationHandler(http.HandlerFunc(ph.UpdateProfileHandler))).
+		Methods(http.MethodPatch, http.MethodOptions)
+
+	ch := AccountClosureHandler{service.NewDefaultAccountClosureService(authRepositoryDb, accountClosureRepositoryDb)}
+	router.
+		Handle("/auth/account/close", amw.AuthenticationHandler(http.HandlerFunc(ch.CloseAccountHandler))).
+		Methods(http.MethodPost, http.MethodOptions)
+
+	dataExportService := service.NewDefaultDataExportService(
+		domain.NewDataExportRepositoryDb(dbClient),
+		authRepositoryDb,
+		customerRepositoryDb,
+		registrationRepositoryDb,
+		kycRepositoryDb,
+		oneTimeTokenRepositoryDb,
+		blobRepository,
+		emailLogRepositoryDb,
+		tokenRepository,
+	)
+	go dataExportService.Cleanup()
+	dh := DataExportHandler{dataExportService}
+	router.
+		Handle("/auth/me/export", amw.AuthenticationHandler(http.HandlerFunc(dh.RequestExportHandler))).
+		Methods(http.MethodPost, http.MethodOptions)
+	router.HandleFunc("/auth/me/export/download", dh.DownloadExportHandler).Methods(http.MethodGet)
+
+	ih := InvitationHandler{service.NewDefaultInvitationService(
+		domain.NewInvitationRepositoryDb(dbClient),
+		tokenRepository,
+		oneTimeTokenRepositoryDb,
+	)}
+	router.HandleFunc("/auth/invitations/mfa", ih.EnrolMfaHandler).Methods(http.MethodPost, http.MethodOptions)
+	router.HandleFunc("/auth/invitations/accept", ih.AcceptInvitationHandler).Methods(http.MethodPost, http.MethodOptions)
+
+	adminRouter := router.PathPrefix("/auth/admin").Subrouter()
+	adminRouter.Use(amw.AuthenticationHandler, amw.AdminHandler)
+	adminRouter.
+		HandleFunc("/registrations/pending", rh.GetRegistrationsPendingReviewHandler).
+		Methods(http.MethodGet, http.MethodOptions).
+		Name("GetPendingRegistrations")
+	adminRouter.
+		HandleFunc("/registrations/approve", rh.ApproveRegistrationHandler).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name("ApproveRegistration")
+	adminRouter.
+		HandleFunc("/registrations/reject", rh.RejectRegistrationHandler).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name("RejectRegistration")
+	adminRouter.
+		HandleFunc("/registrations/resend", rh.ForceResendHandler).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name("ForceResendLink")
+	adminRouter.
+		HandleFunc("/registrations/kyc", kh.GetKycHandler).
+		Methods(http.MethodGet, http.MethodOptions).
+		Name("GetKyc")
+	adminRouter.
+		HandleFunc("/registrations/kyc/document", kh.GetDocumentHandler).
+		Methods(http.MethodGet, http.MethodOptions).
+		Name("GetKycDocument")
+	adminRouter.
+		HandleFunc("/registrations/kyc/verify", kh.VerifyKycHandler).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name("VerifyKyc")
+	adminRouter.
+		HandleFunc("/registrations/kyc/reject", kh.RejectKycHandler).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name("RejectKyc")
+	adminRouter.
+		HandleFunc("/customers/profile/changes", ph.GetProfileChangesHandler).
+		Methods(http.MethodGet, http.MethodOptions).
+		Name("GetProfileChanges")
+	adminRouter.
+		HandleFunc("/closures/pending", ch.GetPendingClosuresHandler).
+		Methods(http.MethodGet, http.MethodOptions).
+		Name("GetPendingClosures")
+	adminRouter.
+		HandleFunc("/closures/erase", ch.EraseCustomerDataHandler).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name("EraseCustomerData")
+	adminRouter.
+		HandleFunc("/closures/hold", ch.PlaceRetentionHoldHandler).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name("PlaceRetentionHold")
+	adminRouter.
+		HandleFunc("/closures/release", ch.ReleaseRetentionHoldHandler).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name("ReleaseRetentionHold")
+	adminRouter.
+		HandleFunc("/invitations", ih.InviteHandler).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name("InviteAdmin")
+
+	oh := EmailOutboxHandler{emailOutboxService}
+	adminRouter.
+		HandleFunc("/emails/outbox", oh.GetOutboxEmailsHandler).
+		Methods(http.MethodGet, http.MethodOptions).
+		Name("GetOutboxEmails")
+	adminRouter.
+		HandleFunc("/emails/outbox/requeue", oh.RequeueEmailHandler).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name("RequeueEmail")
+	lh := EmailLogHandler{service.NewDefaultEmailLogService(emailLogRepositoryDb)}
+	adminRouter.
+		HandleFunc("/emails/log", lh.SearchLogHandler).
+		Methods(http.MethodGet, http.MethodOptions).
+		Name("SearchEmailLog")
+	iah := IpAccessHandler{service.NewDefaultIpAccessService(ipAccessRuleRepositoryDb, ipAccessList)}
+	adminRouter.
+		HandleFunc("/ip-rules", iah.GetRulesHandler).
+		Methods(http.MethodGet, http.MethodOptions).
+		Name("GetIpRules")
+	adminRouter.
+		HandleFunc("/ip-rules", iah.AddRuleHandler).
+		Methods(http.MethodPost).
+		Name(domain.IpAccessRouteAddRule)
+	adminRouter.
+		HandleFunc("/ip-rules/remove", iah.RemoveRuleHandler).
+		Methods(http.MethodPost, http.MethodOptions).
+		Name(domain.IpAccessRouteRemoveRule)
+
+	rmw := RateLimitingMiddleware{domain.NewVisitorRepository(dbClient), domain.NewRateLimitPolicy()}
 	go rmw.repo.Cleanup()
-	router.Use(rmw.RateLimitingHandler)
+	cmw := ClientIpMiddleware{domain.NewTrustedProxies()}
+	router.Use(LocaleHandler, cmw.ClientIpHandler, rmw.RateLimitingHandler)
 
 	address := os.Getenv("SERVER_ADDRESS")
 	port := os.Getenv("SERVER_PORT")
@@ -2,6 +2,7 @@ package app
 
 import (
 	"encoding/json"
+	"github.com/aliciatay-zls/banking-auth/domain"
 	"github.com/aliciatay-zls/banking-auth/dto"
 	"github.com/aliciatay-zls/banking-auth/service"
 	"github.com/aliciatay-zls/banking-lib/errs"
@@ -25,6 +26,7 @@ func (h AuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
 		return
 	}
+	loginRequest.ClientIp = getClientIp(r)
 
 	response, appErr := h.service.Login(loginRequest)
 	if appErr != nil {
@@ -65,6 +67,7 @@ func (h AuthHandler) VerifyHandler(w http.ResponseWriter, r *http.Request) {
 		RouteName:   r.URL.Query().Get("route_name"),
 		CustomerId:  r.URL.Query().Get("customer_id"),
 		AccountId:   r.URL.Query().Get("account_id"),
+		ClientIp:    r.URL.Query().Get("client_ip"),
 	}
 
 	if appErr := h.service.Verify(verifyRequest); appErr != nil {
@@ -117,7 +120,18 @@ func (h AuthHandler) ContinueHandler(w http.ResponseWriter, r *http.Request) {
 	writeJsonResponse(w, http.StatusOK, response)
 }
 
+// writeJsonResponse encodes the given data as the JSON body of the response. Messages (errs.AppError and
+// errs.MessageObject) are translated into the Content-Language of the response, if set by LocaleHandler.
 func writeJsonResponse(w http.ResponseWriter, code int, data interface{}) {
+	if locale := w.Header().Get("Content-Language"); locale != "" {
+		switch msg := data.(type) {
+		case *errs.AppError:
+			data = &errs.AppError{Code: msg.Code, Message: domain.TranslateMessage(locale, msg.Message)}
+		case errs.MessageObject:
+			data = errs.NewMessageObject(domain.TranslateMessage(locale, msg.Message))
+		}
+	}
+
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(code)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-auth/service"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strings"
+)
+
+type contextKey string
+
+const identityContextKey contextKey = "identity"
+
+type AuthenticationMiddleware struct {
+	service      service.AuthService
+	ipAccessList *domain.IpAccessList
+}
+
+// AuthenticationHandler ensures that requests to the auth server's own protected routes carry a valid, non-expired
+// access token as a bearer token in the Authorization header. The identity of the client is stored in the request
+// context for the next handlers to use.
+func (m AuthenticationMiddleware) AuthenticationHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || tokenString == "" {
+			logger.Error("No bearer token in Authorization header")
+			writeJsonResponse(w, http.StatusUnauthorized, errs.NewMessageObject(errs.MessageMissingToken))
+			return
+		}
+
+		identity, appErr := m.service.Authenticate(tokenString)
+		if appErr != nil {
+			writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityContextKey, identity)))
+	})
+}
+
+// AdminHandler ensures that the client is an admin, accessing the route from a network allowed for admins. It must be
+// used after AuthenticationHandler.
+func (m AuthenticationMiddleware) AdminHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if getIdentity(r).Role != domain.RoleAdmin {
+			logger.Error("Client does not have role privileges to access route")
+			writeJsonResponse(w, http.StatusForbidden, errs.NewMessageObject("Trying to access unauthorized route"))
+			return
+		}
+		if appErr := m.ipAccessList.Check(domain.RoleAdmin, mux.CurrentRoute(r).GetName(), getClientIp(r)); appErr != nil {
+			writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// getIdentity returns the identity of the client stored in the request context by AuthenticationHandler.
+func getIdentity(r *http.Request) *dto.Identity {
+	return r.Context().Value(identityContextKey).(*dto.Identity)
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"net/http"
+)
+
+const clientIpContextKey contextKey = "clientIp"
+
+type ClientIpMiddleware struct {
+	proxies domain.TrustedProxies
+}
+
+// ClientIpHandler resolves the IP address of the client from the remote address of the request and, if it came
+// through trusted proxies, their forwarding headers. It is stored in the request context for the next handlers to
+// use, e.g. for rate limiting.
+func (m ClientIpMiddleware) ClientIpHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIp := m.proxies.ResolveClientIp(r.RemoteAddr, r.Header.Values("Forwarded"), r.Header.Values("X-Forwarded-For"))
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIpContextKey, clientIp)))
+	})
+}
+
+// getClientIp returns the IP address of the client stored in the request context by ClientIpHandler.
+func getClientIp(r *http.Request) string {
+	if clientIp, ok := r.Context().Value(clientIpContextKey).(string); ok {
+		return clientIp
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"github.com/aliciatay-zls/banking-auth/service"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+type DataExportHandler struct { //REST handler (adapter)
+	service service.DataExportService
+}
+
+func (h DataExportHandler) RequestExportHandler(w http.ResponseWriter, r *http.Request) {
+	response, appErr := h.service.RequestExport(getIdentity(r))
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusAccepted, response)
+}
+
+func (h DataExportHandler) DownloadExportHandler(w http.ResponseWriter, r *http.Request) {
+	tokenString := r.URL.Query().Get("ott")
+	if tokenString == "" {
+		logger.Error("No token in url")
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(errs.MessageMissingToken))
+		return
+	}
+
+	content, appErr := h.service.DownloadExport(tokenString)
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.Header().Add("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Add("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "data-export.json"}))
+	w.Header().Add("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(content); err != nil {
+		logger.Error("Error while writing data export to response: " + err.Error())
+	}
+}
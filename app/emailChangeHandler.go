@@ -0,0 +1,57 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-auth/service"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net/http"
+)
+
+type EmailChangeHandler struct { //REST handler (adapter)
+	service service.EmailChangeService
+}
+
+func (h EmailChangeHandler) RequestEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	var request dto.EmailChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of email change request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	if appErr := request.Validate(); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	if appErr := h.service.RequestEmailChange(request, getIdentity(r)); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, errs.NewMessageObject(""))
+}
+
+func (h EmailChangeHandler) ConfirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	var request dto.ConfirmEmailChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of confirm email change request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+	if request.Token == "" {
+		logger.Error("One time token missing or empty in request body")
+		writeJsonResponse(w, http.StatusUnprocessableEntity,
+			errs.NewMessageObject("Field missing or empty in request body: one_time_token"))
+		return
+	}
+
+	if appErr := h.service.ConfirmEmailChange(request.Token); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, errs.NewMessageObject(""))
+}
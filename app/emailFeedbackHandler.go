@@ -0,0 +1,48 @@
+package app
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/service"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type EmailFeedbackHandler struct { //REST handler (adapter)
+	service service.EmailSuppressionService
+	secret  string //shared with the mail server or provider posting the reports, no reports are accepted if empty
+}
+
+// ReportHandler accepts a bounce or complaint report posted as a raw message by the mail server or provider, which
+// authenticates itself with the shared secret as a bearer token.
+func (h EmailFeedbackHandler) ReportHandler(w http.ResponseWriter, r *http.Request) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if h.secret == "" || !found || subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		logger.Error("Email feedback report is missing or has an incorrect secret")
+		writeJsonResponse(w, http.StatusUnauthorized, errs.NewMessageObject("Invalid secret"))
+		return
+	}
+
+	report, err := io.ReadAll(http.MaxBytesReader(w, r.Body, domain.MaxFeedbackReportSize))
+	if err != nil {
+		logger.Error("Error while reading email feedback report: " + err.Error())
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJsonResponse(w, http.StatusRequestEntityTooLarge, errs.NewMessageObject("Report must be at most 1 MB"))
+			return
+		}
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	if appErr := h.service.ProcessReport(report); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, errs.NewMessageObject(""))
+}
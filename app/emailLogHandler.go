@@ -0,0 +1,36 @@
+package app
+
+import (
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-auth/service"
+	"net/http"
+)
+
+type EmailLogHandler struct { //REST handler (adapter)
+	service service.EmailLogService
+}
+
+func (h EmailLogHandler) SearchLogHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	request := dto.EmailLogSearchRequest{
+		Recipient: query.Get("recipient"),
+		Template:  query.Get("template"),
+		Type:      query.Get("type"),
+		Status:    query.Get("status"),
+		MessageId: query.Get("message_id"),
+		From:      query.Get("from"),
+		To:        query.Get("to"),
+	}
+	if appErr := request.Validate(); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	response, appErr := h.service.SearchLog(request)
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-auth/service"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net/http"
+)
+
+type EmailOutboxHandler struct { //REST handler (adapter)
+	service service.EmailOutboxService
+}
+
+func (h EmailOutboxHandler) GetOutboxEmailsHandler(w http.ResponseWriter, r *http.Request) {
+	response, appErr := h.service.GetOutboxEmails(r.URL.Query().Get("status"))
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, response)
+}
+
+func (h EmailOutboxHandler) RequeueEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var request dto.OutboxEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of outbox email request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	if appErr := request.Validate(); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	if appErr := h.service.RequeueEmail(request); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, errs.NewMessageObject(""))
+}
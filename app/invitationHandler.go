@@ -0,0 +1,80 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-auth/service"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net/http"
+)
+
+type InvitationHandler struct { //REST handler (adapter)
+	service service.InvitationService
+}
+
+func (h InvitationHandler) InviteHandler(w http.ResponseWriter, r *http.Request) {
+	var request dto.InvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of invitation request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	if appErr := request.Validate(); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	response, appErr := h.service.Invite(request, getIdentity(r).Username)
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusCreated, response)
+}
+
+func (h InvitationHandler) EnrolMfaHandler(w http.ResponseWriter, r *http.Request) {
+	var request dto.MfaEnrolmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of MFA enrolment request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+	if request.Token == "" {
+		logger.Error("One time token missing or empty in request body")
+		writeJsonResponse(w, http.StatusUnprocessableEntity,
+			errs.NewMessageObject("Field missing or empty in request body: one_time_token"))
+		return
+	}
+
+	response, appErr := h.service.EnrolMfa(request.Token)
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, response)
+}
+
+func (h InvitationHandler) AcceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	var request dto.AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of accept invitation request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	if appErr := request.Validate(); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	if appErr := h.service.AcceptInvitation(request); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusCreated, errs.NewMessageObject(""))
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-auth/service"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net/http"
+)
+
+type IpAccessHandler struct { //REST handler (adapter)
+	service service.IpAccessService
+}
+
+func (h IpAccessHandler) GetRulesHandler(w http.ResponseWriter, r *http.Request) {
+	writeJsonResponse(w, http.StatusOK, h.service.GetRules())
+}
+
+func (h IpAccessHandler) AddRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var request dto.IpAccessRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of IP access rule request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	if appErr := request.Validate(); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+	request.ClientIp = getClientIp(r)
+
+	response, appErr := h.service.AddRule(request, getIdentity(r).Username)
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusCreated, response)
+}
+
+func (h IpAccessHandler) RemoveRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var request dto.IpAccessRuleRemovalRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of IP access rule removal request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	if appErr := request.Validate(); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+	request.ClientIp = getClientIp(r)
+
+	if appErr := h.service.RemoveRule(request, getIdentity(r).Username); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, errs.NewMessageObject(""))
+}
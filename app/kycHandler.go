@@ -0,0 +1,140 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-auth/service"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+const kycUploadFormOverhead = 1 << 20 //allowance for the other form fields and multipart boundaries
+
+type KycHandler struct { //REST handler (adapter)
+	service service.KycService
+}
+
+func (h KycHandler) UploadDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, domain.KycDocumentMaxSize+kycUploadFormOverhead)
+	if err := r.ParseMultipartForm(domain.KycDocumentMaxSize); err != nil {
+		logger.Error("Error while parsing multipart form of KYC upload request: " + err.Error())
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJsonResponse(w, http.StatusRequestEntityTooLarge, errs.NewMessageObject("File must be at most 5 MB"))
+			return
+		}
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	file, header, err := r.FormFile("document")
+	if err != nil {
+		logger.Error("Error while getting document from KYC upload request: " + err.Error())
+		writeJsonResponse(w, http.StatusUnprocessableEntity, errs.NewMessageObject("Field missing in request body: document"))
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, domain.KycDocumentMaxSize+1))
+	if err != nil {
+		logger.Error("Error while reading document from KYC upload request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	request := dto.KycUploadRequest{
+		TokenString:  r.FormValue("ott"),
+		DocumentType: r.FormValue("document_type"),
+		FileName:     header.Filename,
+		Content:      content,
+	}
+	if appErr := request.Validate(); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	response, appErr := h.service.UploadDocument(request)
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusCreated, response)
+}
+
+func (h KycHandler) GetKycHandler(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Query().Get("email")
+	if email == "" {
+		logger.Error("No email in url")
+		writeJsonResponse(w, http.StatusUnprocessableEntity, errs.NewMessageObject("Missing email"))
+		return
+	}
+
+	response, appErr := h.service.GetKyc(email)
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, response)
+}
+
+func (h KycHandler) GetDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Query().Get("email")
+	documentId := r.URL.Query().Get("document_id")
+	if email == "" || documentId == "" {
+		logger.Error("No email or document ID in url")
+		writeJsonResponse(w, http.StatusUnprocessableEntity, errs.NewMessageObject("Missing email or document_id"))
+		return
+	}
+
+	doc, content, appErr := h.service.GetDocument(email, documentId)
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	w.Header().Add("Content-Type", doc.ContentType)
+	w.Header().Add("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Add("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": doc.FileName}))
+	w.Header().Add("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(content); err != nil {
+		logger.Error("Error while writing KYC document to response: " + err.Error())
+	}
+}
+
+func (h KycHandler) VerifyKycHandler(w http.ResponseWriter, r *http.Request) {
+	h.reviewKyc(w, r, true)
+}
+
+func (h KycHandler) RejectKycHandler(w http.ResponseWriter, r *http.Request) {
+	h.reviewKyc(w, r, false)
+}
+
+func (h KycHandler) reviewKyc(w http.ResponseWriter, r *http.Request, isVerified bool) {
+	var request dto.ReviewRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of review KYC request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	if appErr := request.Validate(isVerified); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	if appErr := h.service.ReviewKyc(request, isVerified); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, errs.NewMessageObject(""))
+}
@@ -0,0 +1,30 @@
+package app
+
+import (
+	"context"
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"net/http"
+)
+
+const localeContextKey contextKey = "locale"
+
+// LocaleHandler negotiates the locale of the response from the Accept-Language header of the request. The locale is
+// stored in the request context for the next handlers to use, and set as the Content-Language of the response so
+// that writeJsonResponse translates the messages in it.
+func LocaleHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := domain.NegotiateLocale(r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), localeContextKey, locale)))
+	})
+}
+
+// getLocale returns the locale of the request stored in the request context by LocaleHandler.
+func getLocale(r *http.Request) string {
+	if locale, ok := r.Context().Value(localeContextKey).(string); ok {
+		return locale
+	}
+	return domain.DefaultLocale
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-auth/service"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net/http"
+)
+
+type ProfileHandler struct { //REST handler (adapter)
+	service service.ProfileService
+}
+
+func (h ProfileHandler) GetProfileHandler(w http.ResponseWriter, r *http.Request) {
+	response, appErr := h.service.GetProfile(getIdentity(r))
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, response)
+}
+
+func (h ProfileHandler) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	var request dto.ProfileUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of profile update request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	if appErr := request.Validate(); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	response, appErr := h.service.UpdateProfile(request, getIdentity(r))
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, response)
+}
+
+func (h ProfileHandler) GetProfileChangesHandler(w http.ResponseWriter, r *http.Request) {
+	customerId := r.URL.Query().Get("customer_id")
+	if customerId == "" {
+		logger.Error("No customer ID in url")
+		writeJsonResponse(w, http.StatusUnprocessableEntity, errs.NewMessageObject("Missing customer ID"))
+		return
+	}
+
+	response, appErr := h.service.GetProfileChanges(customerId)
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, response)
+}
@@ -1,23 +1,33 @@
 package app
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
 	"github.com/aliciatay-zls/banking-lib/errs"
 	"github.com/aliciatay-zls/banking-lib/logger"
 	"github.com/gorilla/mux"
-	"net"
+	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 )
 
+const rateLimitMaxBodySize = 1 << 20 //only JSON bodies are read, larger ones are left to the handlers to reject
+
 type RateLimitingMiddleware struct {
-	repo domain.VisitorRepository
+	repo   domain.VisitorRepository
+	policy domain.RateLimitPolicy
 }
 
-// RateLimitingHandler ensures that for login and registration routes, requests per user (based on IP address)
-// cannot be too frequent. For all routes, it responds to preflight requests with the necessary headers.
-// Reference used to write this file, visitor.go and visitorRepository.go:
+// RateLimitingHandler ensures that for the routes given in the domain.RateLimitPolicy, requests are not too frequent
+// along any of the dimensions limited for the route (e.g. per IP address and per username). For all routes, it
+// responds to preflight requests with the necessary headers.
+// Reference used to write this file and visitorRepository.go:
 // https://www.alexedwards.net/blog/how-to-rate-limit-http-requests
 func (m *RateLimitingMiddleware) RateLimitingHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -28,30 +38,116 @@ func (m *RateLimitingMiddleware) RateLimitingHandler(next http.Handler) http.Han
 		}
 
 		routeName := mux.CurrentRoute(r).GetName()
-		if routeName == "Login" || routeName == "Register" {
-			ip, _, err := net.SplitHostPort(r.RemoteAddr)
-			if err != nil {
-				logger.Error("Error getting IP address of visitor")
-				writeJsonResponse(w, http.StatusInternalServerError, errs.NewMessageObject("Unexpected server-side error"))
-				return
-			}
+		rules := m.policy[routeName]
+		if len(rules) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-			visitor := m.repo.GetVisitor(ip)
+		values, err := getRateLimitValues(r, rules)
+		if err != nil {
+			logger.Error("Error getting rate limiting information of visitor: " + err.Error())
+			writeJsonResponse(w, http.StatusInternalServerError, errs.NewMessageObject("Unexpected server-side error"))
+			return
+		}
 
-			if !visitor.Limiter.Allow() {
-				logger.Error(fmt.Sprintf("Too many %s requests in 1 sec", routeName))
-				writeJsonResponse(w, http.StatusTooManyRequests, errs.NewMessageObject("Too many attempts"))
-				return
+		checks := make([]domain.RateLimitCheck, 0, len(rules))
+		for i, rule := range rules {
+			value := values[rule.Key]
+			if value == "" { //e.g. no username in the body, left to the handler to reject
+				continue
 			}
+			checks = append(checks, domain.RateLimitCheck{
+				Key:  fmt.Sprintf("%s/%d/%s/%s", routeName, i, rule.Key, value),
+				Rule: rule,
+			})
+		}
+		if len(checks) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		//all the limits are checked together, so a request refused by one does not use up the others
+		statuses, appErr := m.repo.Allow(checks)
+		if appErr != nil {
+			writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+			return
+		}
+		reported := statuses[0] //most restrictive of the limits of the route
+		for i, status := range statuses {
+			if status.IsMoreRestrictiveThan(reported) {
+				reported = status
+			}
+			if !status.IsAllowed {
+				logger.Error(fmt.Sprintf("Too many %s requests per %s in %s", routeName, checks[i].Rule.Key,
+					checks[i].Rule.Period))
+			}
+		}
+		writeRateLimitHeaders(w, reported)
+		if !reported.IsAllowed {
+			writeJsonResponse(w, http.StatusTooManyRequests, errs.NewMessageObject("Too many attempts"))
+			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+// getRateLimitValues returns the value of each dimension that can be limited for the given request, reading the
+// JSON body only if one of the given rules needs it. The body is restored for the handler.
+func getRateLimitValues(r *http.Request, rules []domain.RateLimitRule) (map[string]string, error) {
+	ip := getClientIp(r)
+	if ip == "" {
+		return nil, errors.New("no client IP address")
+	}
+	values := map[string]string{
+		domain.RateLimitKeyIp:       ip,
+		domain.RateLimitKeySubnet:   domain.GetRateLimitSubnet(ip),
+		domain.RateLimitKeyClientIp: domain.NormaliseRateLimitIp(r.URL.Query().Get("client_ip")),
+	}
+
+	if !domain.IsRateLimitedByBody(rules) || r.Body == nil {
+		return values, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, rateLimitMaxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+
+	var fields struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	}
+	_ = json.Unmarshal(body, &fields) //invalid bodies are rejected by the handler
+	values[domain.RateLimitKeyUsername] = domain.NormaliseRateLimitValue(fields.Username)
+	values[domain.RateLimitKeyEmail] = domain.NormaliseRateLimitValue(fields.Email)
+	return values, nil
+}
+
+// writeRateLimitHeaders tells the client about the given limit, with durations in whole seconds rounded up so that
+// retrying after them is never too early.
+func writeRateLimitHeaders(w http.ResponseWriter, status dto.RateLimitStatus) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(status.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(status.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(getCeilSeconds(status.Reset)))
+	if !status.IsAllowed {
+		retryAfter := getCeilSeconds(status.RetryAfter)
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+}
+
+func getCeilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
 func enableCORS(w http.ResponseWriter) {
 	w.Header().Add("Access-Control-Allow-Origin",
 		fmt.Sprintf("https://%s", os.Getenv("FRONTEND_SERVER_DOMAIN")))
 	w.Header().Add("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
 	w.Header().Add("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Add("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
 }
@@ -25,6 +25,9 @@ func (h RegistrationHandler) RegisterHandler(w http.ResponseWriter, r *http.Requ
 		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
 		return
 	}
+	if registrationRequest.Locale == "" {
+		registrationRequest.Locale = getLocale(r)
+	}
 
 	response, appErr := h.service.Register(registrationRequest)
 	if appErr != nil {
@@ -43,17 +46,13 @@ func (h RegistrationHandler) CheckRegistrationHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	isConfirmed, appErr := h.service.CheckRegistration(tokenString)
+	message, appErr := h.service.CheckRegistration(tokenString)
 	if appErr != nil {
 		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
 		return
 	}
-	if isConfirmed {
-		writeJsonResponse(w, http.StatusOK, errs.NewMessageObject("Registration already confirmed"))
-		return
-	}
 
-	writeJsonResponse(w, http.StatusOK, errs.NewMessageObject(""))
+	writeJsonResponse(w, http.StatusOK, errs.NewMessageObject(message))
 }
 
 func (h RegistrationHandler) ResendHandler(w http.ResponseWriter, r *http.Request) {
@@ -75,7 +74,11 @@ func (h RegistrationHandler) ResendHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if appErr := h.service.ResendLink(request); appErr != nil {
+	status, appErr := h.service.ResendLink(request)
+	if status != nil {
+		writeRateLimitHeaders(w, *status)
+	}
+	if appErr != nil {
 		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
 		return
 	}
@@ -97,7 +100,68 @@ func (h RegistrationHandler) FinishRegistrationHandler(w http.ResponseWriter, r
 		return
 	}
 
-	if appErr := h.service.FinishRegistration(request.Token); appErr != nil {
+	message, appErr := h.service.FinishRegistration(request.Token)
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, errs.NewMessageObject(message))
+}
+
+func (h RegistrationHandler) GetRegistrationsPendingReviewHandler(w http.ResponseWriter, r *http.Request) {
+	response, appErr := h.service.GetRegistrationsPendingReview()
+	if appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, response)
+}
+
+func (h RegistrationHandler) ForceResendHandler(w http.ResponseWriter, r *http.Request) {
+	var request dto.ForceResendRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of force resend request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	if appErr := request.Validate(); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	if appErr := h.service.ForceResendLink(request, getIdentity(r).Username); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	writeJsonResponse(w, http.StatusOK, errs.NewMessageObject(""))
+}
+
+func (h RegistrationHandler) ApproveRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	h.reviewRegistration(w, r, true)
+}
+
+func (h RegistrationHandler) RejectRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	h.reviewRegistration(w, r, false)
+}
+
+func (h RegistrationHandler) reviewRegistration(w http.ResponseWriter, r *http.Request, isApproved bool) {
+	var request dto.ReviewRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error("Error while decoding json body of review registration request: " + err.Error())
+		writeJsonResponse(w, http.StatusBadRequest, errs.NewMessageObject(err.Error()))
+		return
+	}
+
+	if appErr := request.Validate(isApproved); appErr != nil {
+		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
+		return
+	}
+
+	if appErr := h.service.ReviewRegistration(request, getIdentity(r).Username, isApproved); appErr != nil {
 		writeJsonResponse(w, appErr.Code, appErr.AsMessage())
 		return
 	}
@@ -0,0 +1,12 @@
+-- Tracks one-time tokens (OTT) sent in confirmation links so that each can only be used once,
+-- and so that sending a new link invalidates older ones.
+CREATE TABLE `one_time_tokens` (
+  `token_id` char(32) NOT NULL,
+  `email` varchar(100) NOT NULL,
+  `created_on` datetime NOT NULL,
+  `expires_on` datetime NOT NULL,
+  `used_on` datetime DEFAULT NULL,
+  `invalidated_on` datetime DEFAULT NULL,
+  PRIMARY KEY (`token_id`),
+  KEY `idx_one_time_tokens_email` (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
@@ -0,0 +1,3 @@
+-- Start of the rolling window within which registrations.email_attempts are counted, so that the limit on resending
+-- confirmation emails is per 24 hours without needing an external job to reset the count.
+ALTER TABLE `registrations` ADD COLUMN `email_window_start` datetime DEFAULT NULL AFTER `email_attempts`;
@@ -0,0 +1,8 @@
+-- Supports the optional approval mode, where registrations with a verified email are reviewed by an admin
+-- (registrations.status 2 = pending review, 3 = rejected) before accounts are opened for them.
+ALTER TABLE `registrations`
+  ADD COLUMN `email_verified_on` datetime DEFAULT NULL AFTER `last_emailed_on`,
+  ADD COLUMN `reviewed_by` varchar(20) DEFAULT NULL,
+  ADD COLUMN `reviewed_on` datetime DEFAULT NULL,
+  ADD COLUMN `review_reason` varchar(255) DEFAULT NULL,
+  ADD KEY `idx_registrations_status` (`status`);
@@ -0,0 +1,23 @@
+-- Accounts opened for newly-registered customers. Products apply to registrations by the most specific scope that
+-- matches: onboarding option and country, onboarding option, country, then the default (both NULL).
+-- Having no active products in a scope means no accounts are opened.
+CREATE TABLE `onboarding_products` (
+  `product_id` int NOT NULL AUTO_INCREMENT,
+  `country_code` char(2) DEFAULT NULL,
+  `onboarding_option` varchar(20) DEFAULT NULL,
+  `account_type` varchar(10) NOT NULL,
+  `opening_amount` decimal(10,2) NOT NULL DEFAULT 0,
+  `status` tinyint NOT NULL DEFAULT 1,
+  `is_active` tinyint NOT NULL DEFAULT 1,
+  PRIMARY KEY (`product_id`),
+  KEY `idx_onboarding_products_scope` (`onboarding_option`, `country_code`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- The demo accounts previously opened for every new customer. Deactivate or delete these in production.
+INSERT INTO `onboarding_products` (`account_type`, `opening_amount`, `status`) VALUES
+  ('saving', 30000, 1),
+  ('checking', 6000, 1);
+
+ALTER TABLE `registrations`
+  ADD COLUMN `country_code` char(2) DEFAULT NULL AFTER `country`,
+  ADD COLUMN `onboarding_option` varchar(20) DEFAULT NULL AFTER `zipcode`;
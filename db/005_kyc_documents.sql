@@ -0,0 +1,19 @@
+-- Metadata of identity documents uploaded during registration (know-your-customer check). The documents themselves
+-- are kept in the blob store.
+CREATE TABLE `kyc_documents` (
+  `document_id` char(32) NOT NULL,
+  `email` varchar(100) NOT NULL,
+  `document_type` varchar(20) NOT NULL,
+  `file_name` varchar(255) NOT NULL,
+  `content_type` varchar(50) NOT NULL,
+  `size_bytes` int NOT NULL,
+  `checksum` char(64) NOT NULL,
+  `storage_key` varchar(100) NOT NULL,
+  `uploaded_on` datetime NOT NULL,
+  PRIMARY KEY (`document_id`),
+  KEY `idx_kyc_documents_email` (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+ALTER TABLE `registrations`
+  ADD COLUMN `kyc_status` varchar(15) NOT NULL DEFAULT 'not_submitted' AFTER `onboarding_option`,
+  ADD COLUMN `kyc_review_reason` varchar(255) DEFAULT NULL AFTER `kyc_status`;
@@ -0,0 +1,5 @@
+-- One-time tokens are now also issued to confirm a change of email, so each token records what it was issued for.
+-- Issuing a new token only invalidates older ones for the same email and purpose.
+ALTER TABLE `one_time_tokens`
+  ADD COLUMN `purpose` varchar(20) NOT NULL DEFAULT 'registration' AFTER `email`,
+  ADD KEY `idx_one_time_tokens_email_purpose` (`email`, `purpose`);
@@ -0,0 +1,12 @@
+-- Audit trail of changes made to customers' profiles, one row per changed field.
+CREATE TABLE `profile_changes` (
+  `change_id` int NOT NULL AUTO_INCREMENT,
+  `customer_id` int NOT NULL,
+  `field` varchar(20) NOT NULL,
+  `old_value` varchar(150) NOT NULL,
+  `new_value` varchar(150) NOT NULL,
+  `changed_by` varchar(20) NOT NULL,
+  `changed_on` datetime NOT NULL,
+  PRIMARY KEY (`change_id`),
+  KEY `idx_profile_changes_customer_id` (`customer_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
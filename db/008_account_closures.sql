@@ -0,0 +1,36 @@
+-- Refresh tokens now record the user they were issued to, so that all of a user's sessions can be ended at once.
+-- Tokens stored before this have no username and simply expire.
+ALTER TABLE `refresh_token_store`
+  ADD COLUMN `username` varchar(20) DEFAULT NULL,
+  ADD KEY `idx_refresh_token_store_username` (`username`);
+
+-- Requests by customers to close their login, and the erasure certificates once their data is erased. The username
+-- is only kept as a hash so that tokens issued before the closure can still be rejected.
+CREATE TABLE `account_closures` (
+  `closure_id` char(32) NOT NULL,
+  `customer_id` int NOT NULL,
+  `username_hash` char(64) NOT NULL,
+  `reason` varchar(255) DEFAULT NULL,
+  `status` varchar(10) NOT NULL,
+  `requested_on` datetime NOT NULL,
+  `erased_by` varchar(20) DEFAULT NULL,
+  `erased_on` datetime DEFAULT NULL,
+  `erased_records` varchar(255) DEFAULT NULL,
+  `certificate_digest` char(64) DEFAULT NULL,
+  PRIMARY KEY (`closure_id`),
+  UNIQUE KEY `idx_account_closures_customer_id` (`customer_id`),
+  KEY `idx_account_closures_username_hash` (`username_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- Holds placed by admins that prevent the data of a customer from being erased until released.
+CREATE TABLE `retention_holds` (
+  `hold_id` int NOT NULL AUTO_INCREMENT,
+  `customer_id` int NOT NULL,
+  `reason` varchar(255) NOT NULL,
+  `placed_by` varchar(20) NOT NULL,
+  `placed_on` datetime NOT NULL,
+  `released_by` varchar(20) DEFAULT NULL,
+  `released_on` datetime DEFAULT NULL,
+  PRIMARY KEY (`hold_id`),
+  KEY `idx_retention_holds_customer_id` (`customer_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
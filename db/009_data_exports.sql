@@ -0,0 +1,14 @@
+-- Archives of personal data requested by customers. The archives themselves are kept in the blob store until the
+-- download link expires.
+CREATE TABLE `data_exports` (
+  `export_id` char(32) NOT NULL,
+  `customer_id` int NOT NULL,
+  `username` varchar(20) NOT NULL,
+  `status` varchar(10) NOT NULL,
+  `storage_key` varchar(100) NOT NULL,
+  `requested_on` datetime NOT NULL,
+  `ready_on` datetime DEFAULT NULL,
+  PRIMARY KEY (`export_id`),
+  KEY `idx_data_exports_customer_id` (`customer_id`),
+  KEY `idx_data_exports_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
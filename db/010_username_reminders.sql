@@ -0,0 +1,9 @@
+-- Tracks emails sent to remind clients of their usernames, so that they are limited in the same way as resending
+-- confirmation links (see registrations.email_attempts).
+CREATE TABLE `username_reminders` (
+  `email` varchar(100) NOT NULL,
+  `email_attempts` int NOT NULL DEFAULT '0',
+  `email_window_start` datetime DEFAULT NULL,
+  `last_emailed_on` datetime DEFAULT NULL,
+  PRIMARY KEY (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
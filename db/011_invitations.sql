@@ -0,0 +1,22 @@
+-- Invitations sent by admins for others to become admins (users without a customer ID).
+CREATE TABLE `invitations` (
+  `invitation_id` char(32) NOT NULL,
+  `email` varchar(100) NOT NULL,
+  `invited_by` varchar(20) NOT NULL,
+  `mfa_secret` varchar(32) DEFAULT NULL,
+  `created_on` datetime NOT NULL,
+  `expires_on` datetime NOT NULL,
+  `accepted_on` datetime DEFAULT NULL,
+  `accepted_username` varchar(20) DEFAULT NULL,
+  PRIMARY KEY (`invitation_id`),
+  KEY `idx_invitations_email` (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- TOTP authenticators enrolled by users. Users with one enrolled need to give a code from it when logging in.
+CREATE TABLE `user_mfa` (
+  `username` varchar(20) NOT NULL,
+  `totp_secret` varchar(32) NOT NULL,
+  `last_used_step` bigint DEFAULT NULL,
+  `enrolled_on` datetime NOT NULL,
+  PRIMARY KEY (`username`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
@@ -0,0 +1,4 @@
+-- Preferred language of each customer, chosen at sign-up (or negotiated from the Accept-Language header) and used
+-- for the emails sent to them. Existing registrations keep receiving English emails.
+ALTER TABLE `registrations`
+  ADD COLUMN `locale` varchar(10) NOT NULL DEFAULT 'en' AFTER `zipcode`;
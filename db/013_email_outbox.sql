@@ -0,0 +1,18 @@
+-- Emails waiting to be delivered by the background worker, written in the same transaction as the change they are
+-- about (e.g. a new registration) so that an email is sent if and only if the change is saved. Emails that still fail
+-- after the maximum attempts are dead-lettered until an admin requeues them.
+CREATE TABLE `email_outbox` (
+  `outbox_id` bigint NOT NULL AUTO_INCREMENT,
+  `recipient` varchar(100) NOT NULL,
+  `locale` varchar(10) NOT NULL DEFAULT 'en',
+  `template` varchar(50) NOT NULL,
+  `data` text DEFAULT NULL,
+  `status` varchar(10) NOT NULL DEFAULT 'pending',
+  `attempts` int NOT NULL DEFAULT 0,
+  `next_attempt_on` datetime NOT NULL,
+  `last_error` varchar(255) DEFAULT NULL,
+  `created_on` datetime NOT NULL,
+  `sent_on` datetime DEFAULT NULL,
+  PRIMARY KEY (`outbox_id`),
+  KEY `idx_email_outbox_status_next_attempt_on` (`status`, `next_attempt_on`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
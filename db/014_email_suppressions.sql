@@ -0,0 +1,9 @@
+-- Addresses that must not be sent confirmation links anymore, as a bounce report showed that they cannot receive
+-- emails or a complaint report showed that their owner marked an email as spam.
+CREATE TABLE `email_suppressions` (
+  `email` varchar(100) NOT NULL,
+  `reason` varchar(10) NOT NULL,
+  `diagnostic` varchar(255) DEFAULT NULL,
+  `suppressed_on` datetime NOT NULL,
+  PRIMARY KEY (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
@@ -0,0 +1,30 @@
+-- Record of every attempt at sending an email, successful or not, for support and auditing. Unlike the outbox, it
+-- also covers emails sent right away, and it never holds the content of the emails.
+CREATE TABLE `email_log` (
+  `log_id` bigint NOT NULL AUTO_INCREMENT,
+  `type` varchar(10) NOT NULL,
+  `recipient` varchar(100) NOT NULL,
+  `template` varchar(50) NOT NULL,
+  `locale` varchar(10) NOT NULL,
+  `outbox_id` bigint DEFAULT NULL,
+  `transport` varchar(10) NOT NULL,
+  `status` varchar(10) NOT NULL,
+  `result` varchar(255) DEFAULT NULL,
+  `message_id` varchar(255) DEFAULT NULL,
+  `attempted_on` datetime NOT NULL,
+  PRIMARY KEY (`log_id`),
+  KEY `idx_email_log_recipient` (`recipient`),
+  KEY `idx_email_log_message_id` (`message_id`),
+  KEY `idx_email_log_attempted_on` (`attempted_on`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Confirmation links resent by an admin regardless of the limits on resending, with the reason given.
+CREATE TABLE `forced_resends` (
+  `resend_id` bigint NOT NULL AUTO_INCREMENT,
+  `email` varchar(100) NOT NULL,
+  `resent_by` varchar(20) NOT NULL,
+  `reason` varchar(255) NOT NULL,
+  `resent_on` datetime NOT NULL,
+  PRIMARY KEY (`resend_id`),
+  KEY `idx_forced_resends_email` (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
@@ -0,0 +1,4 @@
+-- Records where and when each session (refresh token) was started, for auditing.
+ALTER TABLE `refresh_token_store`
+  ADD COLUMN `client_ip` varchar(45) DEFAULT NULL,
+  ADD COLUMN `created_on` datetime DEFAULT NULL;
@@ -0,0 +1,8 @@
+-- Rate limiting state shared by all instances of the server when RATE_LIMIT_STORE is db. tat is the theoretical
+-- arrival time of the next request, in microseconds since the Unix epoch.
+CREATE TABLE `rate_limit_visitors` (
+  `visitor_key` varchar(255) NOT NULL,
+  `tat` bigint NOT NULL,
+  PRIMARY KEY (`visitor_key`),
+  KEY `idx_rate_limit_visitors_tat` (`tat`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
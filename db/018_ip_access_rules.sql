@@ -0,0 +1,12 @@
+-- Networks that each role is allowed or denied access from, at login and in Verify, to a route or to all routes if
+-- route_name is empty. Managed by admins and reloaded periodically by every instance of the server.
+CREATE TABLE `ip_access_rules` (
+  `rule_id` bigint NOT NULL AUTO_INCREMENT,
+  `role` varchar(20) NOT NULL,
+  `route_name` varchar(50) NOT NULL DEFAULT '',
+  `cidr` varchar(50) NOT NULL,
+  `action` varchar(10) NOT NULL,
+  `created_by` varchar(20) NOT NULL,
+  `created_on` datetime NOT NULL,
+  PRIMARY KEY (`rule_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
@@ -0,0 +1,3 @@
+-- Records the role that an invitation gives its invitee, which must be one without a customer ID.
+ALTER TABLE `invitations`
+  ADD COLUMN `role` varchar(20) NOT NULL DEFAULT 'admin' AFTER `email`;
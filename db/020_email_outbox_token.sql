@@ -0,0 +1,5 @@
+-- Links emails in the outbox to the one-time token they are about (e.g. the email delivering its link), so that they
+-- can be removed along with it, e.g. when the customer closes their account before confirming a change of email.
+ALTER TABLE `email_outbox`
+  ADD COLUMN `token_id` char(32) DEFAULT NULL AFTER `template`,
+  ADD KEY `idx_email_outbox_token_id` (`token_id`);
@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"os"
+	"strings"
+	"time"
+)
+
+const AccountClosureStatusRequested = "requested"
+const AccountClosureStatusErased = "erased"
+const DefaultErasureRetentionPeriod = time.Hour * 24 * 30
+
+type AccountClosure struct { //business/domain object
+	ClosureId     string         `db:"closure_id"`
+	CustomerId    string         `db:"customer_id"`
+	UsernameHash  string         `db:"username_hash"` //so that tokens of the closed login can still be revoked after erasure
+	Reason        sql.NullString `db:"reason"`
+	Status        string         `db:"status"`
+	DateRequested string         `db:"requested_on"`
+
+	ErasedBy          sql.NullString `db:"erased_by"` //username of the admin who ran the erasure
+	DateErased        sql.NullString `db:"erased_on"`
+	ErasedRecords     sql.NullString `db:"erased_records"`     //number of records erased per table
+	CertificateDigest sql.NullString `db:"certificate_digest"` //sha256 over the details of the erasure
+
+	IsOnHold bool `db:"is_on_hold"` //not stored, whether a RetentionHold is currently in place for the customer
+}
+
+// RetentionHold prevents the data of a customer from being erased while it is in place, e.g. because of an ongoing
+// investigation or legal claim.
+type RetentionHold struct {
+	CustomerId   string         `db:"customer_id"`
+	Reason       string         `db:"reason"`
+	PlacedBy     string         `db:"placed_by"`
+	DatePlaced   string         `db:"placed_on"`
+	ReleasedBy   sql.NullString `db:"released_by"`
+	DateReleased sql.NullString `db:"released_on"`
+}
+
+// NewAccountClosure creates a new request by the given customer to close their login, identified by a random
+// unique ID.
+func NewAccountClosure(customerId string, username string, reason string) (*AccountClosure, *errs.AppError) {
+	closureId, err := GenerateRandomId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountClosure{
+		ClosureId:     closureId,
+		CustomerId:    customerId,
+		UsernameHash:  hashUsername(username),
+		Reason:        sql.NullString{String: reason, Valid: reason != ""},
+		Status:        AccountClosureStatusRequested,
+		DateRequested: time.Now().UTC().Format(FormatDateTime),
+	}, nil
+}
+
+// hashUsername pseudonymises the given username so that closures can be looked up by username without keeping it.
+func hashUsername(username string) string {
+	h := sha256.Sum256([]byte(username))
+	return hex.EncodeToString(h[:])
+}
+
+func (c AccountClosure) IsErased() bool {
+	return c.Status == AccountClosureStatusErased
+}
+
+// CanBeErased checks that the data of the customer has not been erased yet and has been kept for at least the
+// retention period since the closure was requested.
+func (c AccountClosure) CanBeErased(now time.Time) *errs.AppError {
+	if c.IsErased() {
+		logger.Error("Cannot erase data of customer as it is already erased")
+		return errs.NewConflictError("Customer data already erased")
+	}
+
+	requestTime, err := time.Parse(FormatDateTime, c.DateRequested)
+	if err != nil {
+		logger.Error("Error while parsing time of account closure request: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected server-side error")
+	}
+	if now.Before(requestTime.Add(GetErasureRetentionPeriod())) {
+		logger.Error("Cannot erase data of customer as the retention period is not over yet")
+		return errs.NewConflictError("Retention period not over yet")
+	}
+
+	return nil
+}
+
+// Erase records that the data of the customer was erased by the given admin at the given time, with the given number
+// of records erased per table, and computes the digest of the erasure certificate over these details.
+func (c *AccountClosure) Erase(erasedBy string, erasedOn string, erasedRecords string) {
+	c.Status = AccountClosureStatusErased
+	c.ErasedBy = sql.NullString{String: erasedBy, Valid: true}
+	c.DateErased = sql.NullString{String: erasedOn, Valid: true}
+	c.ErasedRecords = sql.NullString{String: erasedRecords, Valid: true}
+
+	h := sha256.Sum256([]byte(strings.Join(
+		[]string{c.ClosureId, c.CustomerId, c.UsernameHash, c.DateRequested, erasedBy, erasedOn, erasedRecords}, "|")))
+	c.CertificateDigest = sql.NullString{String: hex.EncodeToString(h[:]), Valid: true}
+}
+
+func (c AccountClosure) ToDTO() dto.AccountClosureResponse {
+	return dto.AccountClosureResponse{
+		ClosureId:     c.ClosureId,
+		CustomerId:    c.CustomerId,
+		Reason:        c.Reason.String,
+		Status:        c.Status,
+		DateRequested: c.DateRequested,
+		IsOnHold:      c.IsOnHold,
+	}
+}
+
+func (c AccountClosure) ToCertificateDTO() *dto.ErasureCertificateResponse {
+	return &dto.ErasureCertificateResponse{
+		ClosureId:     c.ClosureId,
+		CustomerId:    c.CustomerId,
+		DateRequested: c.DateRequested,
+		ErasedBy:      c.ErasedBy.String,
+		DateErased:    c.DateErased.String,
+		ErasedRecords: c.ErasedRecords.String,
+		Digest:        c.CertificateDigest.String,
+	}
+}
+
+// GetErasureRetentionPeriod returns how long the data of a customer has to be kept after they requested to close
+// their login before it can be erased, which is configured using the ERASURE_RETENTION_PERIOD environment variable.
+func GetErasureRetentionPeriod() time.Duration {
+	val := os.Getenv("ERASURE_RETENTION_PERIOD")
+	if val == "" {
+		return DefaultErasureRetentionPeriod
+	}
+
+	period, err := time.ParseDuration(val)
+	if err != nil || period <= 0 {
+		logger.Error("Invalid erasure retention period, using default instead")
+		return DefaultErasureRetentionPeriod
+	}
+	return period
+}
@@ -0,0 +1,299 @@
+package domain
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type AccountClosureRepository interface { //repo (secondary port)
+	Save(AccountClosure, string) *errs.AppError
+	IsClosed(string) (bool, *errs.AppError)
+	FindPending() ([]AccountClosure, *errs.AppError)
+	Erase(string, string, time.Time) (*AccountClosure, *errs.AppError)
+	PlaceHold(RetentionHold) *errs.AppError
+	ReleaseHold(string, string, string) *errs.AppError
+}
+
+type AccountClosureRepositoryDb struct { //DB (adapter)
+	client *sqlx.DB
+}
+
+func NewAccountClosureRepositoryDb(dbClient *sqlx.DB) AccountClosureRepositoryDb {
+	return AccountClosureRepositoryDb{dbClient}
+}
+
+const accountClosureColumns = `closure_id, customer_id, username_hash, reason, status, requested_on, 
+	erased_by, erased_on, erased_records, certificate_digest`
+
+// Save records the given AccountClosure and ends all sessions of the user with the given username by removing their
+// refresh tokens from the store, in a single db transaction. Pending changes of email are cancelled too: their links
+// are invalidated and their emails not yet sent are removed from the outbox. A customer can only close their login
+// once.
+func (d AccountClosureRepositoryDb) Save(closure AccountClosure, username string) *errs.AppError {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for saving account closure: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	var isExists bool
+	findSql := "SELECT EXISTS(SELECT 1 FROM account_closures WHERE customer_id = ?)"
+	if err = tx.Get(&isExists, findSql, closure.CustomerId); err != nil {
+		logger.Error("Error while checking if account closure already exists: " + err.Error())
+		rollbackTx(tx, "checking of account closure")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	if isExists {
+		rollbackTx(tx, "checking of existing account closure")
+		logger.Error("Account closure already requested for customer")
+		return errs.NewConflictError("Account already closed")
+	}
+
+	insertSql := `INSERT INTO account_closures (closure_id, customer_id, username_hash, reason, status, requested_on) 
+		VALUES (:closure_id, :customer_id, :username_hash, :reason, :status, :requested_on)`
+	if _, err = tx.NamedExec(insertSql, closure); err != nil {
+		logger.Error("Error while saving account closure: " + err.Error())
+		rollbackTx(tx, "saving of account closure")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if _, err = tx.Exec("DELETE FROM refresh_token_store WHERE username = ?", username); err != nil {
+		logger.Error("Error while revoking refresh tokens of closed login: " + err.Error())
+		rollbackTx(tx, "saving of account closure")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	pendingTokensSql := `SELECT token_id FROM one_time_tokens WHERE purpose = ? 
+		AND email = (SELECT email FROM customers WHERE customer_id = ?)`
+	deleteEmailsSql := "DELETE FROM email_outbox WHERE status <> ? AND token_id IN (" + pendingTokensSql + ")"
+	if _, err = tx.Exec(deleteEmailsSql, EmailOutboxStatusSent, OneTimeTokenPurposeEmailChange, closure.CustomerId); err != nil {
+		logger.Error("Error while removing unsent email change emails of closed login: " + err.Error())
+		rollbackTx(tx, "saving of account closure")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	invalidateSql := `UPDATE one_time_tokens SET invalidated_on = ? WHERE purpose = ? AND used_on IS NULL 
+		AND invalidated_on IS NULL AND email = (SELECT email FROM customers WHERE customer_id = ?)`
+	if _, err = tx.Exec(invalidateSql, closure.DateRequested, OneTimeTokenPurposeEmailChange, closure.CustomerId); err != nil {
+		logger.Error("Error while invalidating email change links of closed login: " + err.Error())
+		rollbackTx(tx, "saving of account closure")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for saving account closure: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// IsClosed checks whether the user with the given username has closed their login, so that tokens issued to them
+// are no longer accepted. Once the data of the customer is erased, this only holds for as long as an access token
+// issued before could still be valid, so that the username can be used again afterwards.
+func (d AccountClosureRepositoryDb) IsClosed(username string) (bool, *errs.AppError) {
+	var isClosed bool
+	cutoff := time.Now().UTC().Add(-AccessTokenDuration).Format(FormatDateTime)
+	findSql := `SELECT EXISTS(SELECT 1 FROM account_closures 
+		WHERE username_hash = ? AND (status = ? OR erased_on > ?))`
+	if err := d.client.Get(&isClosed, findSql, hashUsername(username), AccountClosureStatusRequested, cutoff); err != nil {
+		logger.Error("Error while checking if login is closed: " + err.Error())
+		return false, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return isClosed, nil
+}
+
+// FindPending retrieves all account closures whose customer data has not been erased yet, oldest first, along with
+// whether each is currently on hold.
+func (d AccountClosureRepositoryDb) FindPending() ([]AccountClosure, *errs.AppError) {
+	closures := make([]AccountClosure, 0)
+	findSql := `SELECT ` + accountClosureColumns + `, 
+		EXISTS(SELECT 1 FROM retention_holds h WHERE h.customer_id = c.customer_id AND h.released_on IS NULL) AS is_on_hold 
+		FROM account_closures c WHERE status = ? ORDER BY requested_on`
+	if err := d.client.Select(&closures, findSql, AccountClosureStatusRequested); err != nil {
+		logger.Error("Error while retrieving pending account closures: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return closures, nil
+}
+
+// Erase removes or pseudonymises the data held about the customer with the given customer ID, on behalf of the given
+// admin at the given time, in a single db transaction: the closure is locked and checked to be past its retention
+// period without any retention hold, the user and their sessions, one-time tokens and username reminders are removed,
+// the registration is pseudonymised, the profile audit trail is removed and data exports are pseudonymised. The closure
+// is then updated with the erasure certificate.
+//
+// KYC documents of the registration are left to the KYC cleanup, which removes them once their registration email no
+// longer exists. The customer record and bank accounts belong to the banking server and are not touched.
+func (d AccountClosureRepositoryDb) Erase(customerId string, erasedBy string, erasedOn time.Time) (*AccountClosure, *errs.AppError) {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for erasing customer data: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	var closure AccountClosure
+	lockSql := "SELECT " + accountClosureColumns + " FROM account_closures WHERE customer_id = ? FOR UPDATE"
+	if err = tx.Get(&closure, lockSql, customerId); err != nil {
+		rollbackTx(tx, "locking of account closure")
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("No account closure requested for the given customer")
+			return nil, errs.NewNotFoundError("Account closure not found")
+		}
+		logger.Error("Error while locking account closure: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if appErr := closure.CanBeErased(erasedOn); appErr != nil {
+		rollbackTx(tx, "checking of account closure")
+		return nil, appErr
+	}
+
+	var isOnHold bool
+	findHoldSql := "SELECT EXISTS(SELECT 1 FROM retention_holds WHERE customer_id = ? AND released_on IS NULL)"
+	if err = tx.Get(&isOnHold, findHoldSql, customerId); err != nil {
+		logger.Error("Error while checking for retention hold: " + err.Error())
+		rollbackTx(tx, "checking for retention hold")
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+	if isOnHold {
+		rollbackTx(tx, "checking for retention hold")
+		logger.Error("Cannot erase data of customer as it is under a retention hold")
+		return nil, errs.NewConflictError("Customer data is under a retention hold")
+	}
+
+	var reg struct {
+		Email    string
+		Username string
+	}
+	if err = tx.Get(&reg, "SELECT email, username FROM registrations WHERE customer_id = ?", customerId); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Error("Error while retrieving registration to erase: " + err.Error())
+		rollbackTx(tx, "erasing of customer data")
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+	var username string
+	if err = tx.Get(&username, "SELECT username FROM users WHERE customer_id = ?", customerId); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Error("Error while retrieving user to erase: " + err.Error())
+		rollbackTx(tx, "erasing of customer data")
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	pseudonym := "erased_" + closure.ClosureId[:13] //fits the maximum username length
+	steps := []struct {
+		Table string
+		Sql   string
+		Args  []interface{}
+	}{
+		{"refresh_token_store", "DELETE FROM refresh_token_store WHERE username = ?", []interface{}{username}},
+		{"user_mfa", "DELETE FROM user_mfa WHERE username = ?", []interface{}{username}},
+		{"users", "DELETE FROM users WHERE customer_id = ?", []interface{}{customerId}},
+		//including the emails about the customer's one-time tokens, e.g. to the new address of a change of email
+		{"email_outbox", `DELETE FROM email_outbox WHERE recipient = ? 
+			OR token_id IN (SELECT token_id FROM one_time_tokens WHERE email = ?)`, []interface{}{reg.Email, reg.Email}},
+		{"one_time_tokens", "DELETE FROM one_time_tokens WHERE email = ?", []interface{}{reg.Email}},
+		{"username_reminders", "DELETE FROM username_reminders WHERE email = ?", []interface{}{reg.Email}},
+		{"email_suppressions", "DELETE FROM email_suppressions WHERE email = ?", []interface{}{reg.Email}},
+		//kept for auditing, but no longer linked to the customer
+		{"email_log", "UPDATE email_log SET recipient = ? WHERE recipient = ?",
+			[]interface{}{pseudonym + "@erased.invalid", reg.Email}},
+		{"forced_resends", "UPDATE forced_resends SET email = ? WHERE email = ?",
+			[]interface{}{pseudonym + "@erased.invalid", reg.Email}},
+		{"registrations", `UPDATE registrations SET email = ?, name = ?, date_of_birth = ?, zipcode = ?, username = ?, 
+			password = '', review_reason = NULL, kyc_review_reason = NULL WHERE customer_id = ?`,
+			[]interface{}{pseudonym + "@erased.invalid", pseudonym, "1900-01-01", "", pseudonym, customerId}},
+		{"profile_changes", "DELETE FROM profile_changes WHERE customer_id = ?", []interface{}{customerId}},
+		//archives not yet removed are marked as failed so that the data export cleanup removes them
+		{"data_exports", `UPDATE data_exports SET username = ?, status = IF(status = 'expired', status, 'failed') 
+			WHERE customer_id = ?`, []interface{}{pseudonym, customerId}},
+	}
+	erasedRecords := ""
+	for i, step := range steps {
+		result, execErr := tx.Exec(step.Sql, step.Args...)
+		if execErr != nil {
+			logger.Error("Error while erasing customer data from " + step.Table + ": " + execErr.Error())
+			rollbackTx(tx, "erasing of customer data")
+			return nil, errs.NewUnexpectedError("Unexpected database error")
+		}
+		rowsAffected, execErr := result.RowsAffected()
+		if execErr != nil {
+			logger.Error("Error while counting erased customer data from " + step.Table + ": " + execErr.Error())
+			rollbackTx(tx, "erasing of customer data")
+			return nil, errs.NewUnexpectedError("Unexpected database error")
+		}
+		if i > 0 {
+			erasedRecords += ", "
+		}
+		erasedRecords += fmt.Sprintf("%s=%d", step.Table, rowsAffected)
+	}
+
+	closure.Erase(erasedBy, erasedOn.Format(FormatDateTime), erasedRecords)
+	updateSql := `UPDATE account_closures SET status = :status, erased_by = :erased_by, erased_on = :erased_on, 
+		erased_records = :erased_records, certificate_digest = :certificate_digest WHERE closure_id = :closure_id`
+	if _, err = tx.NamedExec(updateSql, closure); err != nil {
+		logger.Error("Error while recording erasure certificate: " + err.Error())
+		rollbackTx(tx, "erasing of customer data")
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for erasing customer data: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return &closure, nil
+}
+
+// PlaceHold records the given RetentionHold, unless one is already in place for the customer.
+func (d AccountClosureRepositoryDb) PlaceHold(hold RetentionHold) *errs.AppError {
+	insertSql := `INSERT INTO retention_holds (customer_id, reason, placed_by, placed_on) 
+		SELECT :customer_id, :reason, :placed_by, :placed_on FROM DUAL 
+		WHERE NOT EXISTS (SELECT 1 FROM retention_holds WHERE customer_id = :customer_id AND released_on IS NULL)`
+	result, err := d.client.NamedExec(insertSql, hold)
+	if err != nil {
+		logger.Error("Error while placing retention hold: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	rowsInserted, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Error while checking that retention hold was placed: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	if rowsInserted != 1 {
+		logger.Error("Retention hold already in place for customer")
+		return errs.NewConflictError("Retention hold already in place")
+	}
+
+	return nil
+}
+
+// ReleaseHold releases the retention hold in place for the customer with the given customer ID, on behalf of the
+// given admin at the given time.
+func (d AccountClosureRepositoryDb) ReleaseHold(customerId string, releasedBy string, releasedOn string) *errs.AppError {
+	updateSql := `UPDATE retention_holds SET released_by = ?, released_on = ? 
+		WHERE customer_id = ? AND released_on IS NULL`
+	result, err := d.client.Exec(updateSql, releasedBy, releasedOn, customerId)
+	if err != nil {
+		logger.Error("Error while releasing retention hold: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	rowsUpdated, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Error while checking that retention hold was released: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	if rowsUpdated != 1 {
+		logger.Error("No retention hold in place for customer")
+		return errs.NewNotFoundError("Retention hold not found")
+	}
+
+	return nil
+}
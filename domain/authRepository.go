@@ -6,13 +6,17 @@ import (
 	"github.com/aliciatay-zls/banking-lib/errs"
 	"github.com/aliciatay-zls/banking-lib/logger"
 	"github.com/jmoiron/sqlx"
+	"time"
 )
 
 type AuthRepository interface { //repo (secondary port)
 	Authenticate(string, string) (*Auth, *errs.AppError)
-	SaveRefreshTokenToStore(string) *errs.AppError
+	SaveRefreshTokenToStore(string, string, string) *errs.AppError
 	DeleteRefreshTokenFromStore(string) *errs.AppError
 	FindRefreshToken(string) (bool, *errs.AppError)
+	FindSessions(string) ([]Session, *errs.AppError)
+	FindTotpSecret(string) (string, *errs.AppError)
+	UseTotpStep(string, int64) *errs.AppError
 	FindUser(string, string, string) (*Auth, *errs.AppError)
 	IsAccountUnderCustomer(string, string) *errs.AppError
 }
@@ -43,9 +47,12 @@ func (d AuthRepositoryDb) Authenticate(un string, pw string) (*Auth, *errs.AppEr
 	return &auth, nil
 }
 
-func (d AuthRepositoryDb) SaveRefreshTokenToStore(refreshToken string) *errs.AppError {
-	insertTokenSql := `INSERT INTO refresh_token_store (refresh_token) VALUES (?)`
-	if _, err := d.client.Exec(insertTokenSql, refreshToken); err != nil {
+// SaveRefreshTokenToStore stores the given refresh token along with the username of the user it was issued to, so that
+// all of a user's sessions can be ended at once, and the IP address of the client that logged in, for auditing.
+func (d AuthRepositoryDb) SaveRefreshTokenToStore(refreshToken string, username string, clientIp string) *errs.AppError {
+	insertTokenSql := `INSERT INTO refresh_token_store (refresh_token, username, client_ip, created_on) VALUES (?, ?, ?, ?)`
+	createdOn := time.Now().UTC().Format(FormatDateTime)
+	if _, err := d.client.Exec(insertTokenSql, refreshToken, username, clientIp, createdOn); err != nil {
 		logger.Error("Error while storing refresh token: " + err.Error())
 		return errs.NewUnexpectedError("Unexpected database error")
 	}
@@ -89,6 +96,57 @@ func (d AuthRepositoryDb) FindRefreshToken(token string) (bool, *errs.AppError)
 	return isExists, nil
 }
 
+// FindSessions retrieves where and when the sessions (refresh tokens in the store) of the user with the given
+// username were started, i.e. the sessions the user has not logged out of, the latest first.
+func (d AuthRepositoryDb) FindSessions(username string) ([]Session, *errs.AppError) {
+	sessions := make([]Session, 0)
+	findSql := "SELECT client_ip, created_on FROM refresh_token_store WHERE username = ? ORDER BY created_on DESC"
+	if err := d.client.Select(&sessions, findSql, username); err != nil {
+		logger.Error("Error while retrieving sessions: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return sessions, nil
+}
+
+// FindTotpSecret retrieves the TOTP secret enrolled by the user with the given username. An empty secret is returned
+// instead of an error if the user has not enrolled any.
+func (d AuthRepositoryDb) FindTotpSecret(username string) (string, *errs.AppError) {
+	var secret string
+	if err := d.client.Get(&secret, "SELECT totp_secret FROM user_mfa WHERE username = ?", username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		logger.Error("Error while retrieving TOTP secret: " + err.Error())
+		return "", errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return secret, nil
+}
+
+// UseTotpStep records that the TOTP code of the given time step was used by the user with the given username. This
+// is done atomically and only for steps later than the last one used, so that each code can only be used once.
+func (d AuthRepositoryDb) UseTotpStep(username string, step int64) *errs.AppError {
+	updateSql := "UPDATE user_mfa SET last_used_step = ? WHERE username = ? AND (last_used_step IS NULL OR last_used_step < ?)"
+	result, err := d.client.Exec(updateSql, step, username, step)
+	if err != nil {
+		logger.Error("Error while recording use of TOTP code: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	rowsUpdated, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Error while checking that use of TOTP code was recorded: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	if rowsUpdated != 1 {
+		logger.Error("TOTP code was already used")
+		return errs.NewAuthenticationError("Incorrect username, password or MFA code")
+	}
+
+	return nil
+}
+
 func (d AuthRepositoryDb) FindUser(un string, role string, cid string) (*Auth, *errs.AppError) {
 	var auth Auth
 	var err error
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"errors"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const DefaultBlobStoragePath = "blobs"
+
+type BlobRepository interface { //repo (secondary port)
+	Put(string, []byte) *errs.AppError
+	Get(string) ([]byte, *errs.AppError)
+	Delete(string) *errs.AppError
+}
+
+// LocalBlobRepository is a blob store keeping each blob as a file on the local filesystem, under the directory
+// specified by the BLOB_STORAGE_PATH environment variable (DefaultBlobStoragePath if not set).
+type LocalBlobRepository struct { //adapter
+	rootDir string
+}
+
+func NewLocalBlobRepository() LocalBlobRepository {
+	rootDir := os.Getenv("BLOB_STORAGE_PATH")
+	if rootDir == "" {
+		rootDir = DefaultBlobStoragePath
+	}
+	if err := os.MkdirAll(rootDir, 0700); err != nil {
+		logger.Fatal("Error while creating blob storage directory: " + err.Error())
+	}
+
+	return LocalBlobRepository{rootDir}
+}
+
+// Put writes the given content to the blob with the given key, replacing any existing content.
+func (r LocalBlobRepository) Put(key string, content []byte) *errs.AppError {
+	path, appErr := r.getPath(key)
+	if appErr != nil {
+		return appErr
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		logger.Error("Error while creating directory for blob: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected server-side error")
+	}
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		logger.Error("Error while writing blob: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected server-side error")
+	}
+
+	return nil
+}
+
+// Get reads the content of the blob with the given key.
+func (r LocalBlobRepository) Get(key string) ([]byte, *errs.AppError) {
+	path, appErr := r.getPath(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			logger.Error("Blob does not exist")
+			return nil, errs.NewNotFoundError("File not found")
+		}
+		logger.Error("Error while reading blob: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected server-side error")
+	}
+
+	return content, nil
+}
+
+// Delete removes the blob with the given key. Deleting a blob that does not exist is not an error.
+func (r LocalBlobRepository) Delete(key string) *errs.AppError {
+	path, appErr := r.getPath(key)
+	if appErr != nil {
+		return appErr
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Error("Error while deleting blob: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected server-side error")
+	}
+
+	return nil
+}
+
+// getPath maps the given key to a file path under the root directory, rejecting keys that could escape it.
+func (r LocalBlobRepository) getPath(key string) (string, *errs.AppError) {
+	if key == "" || filepath.IsAbs(key) || strings.Contains(key, "..") || strings.Contains(key, "\\") {
+		logger.Error("Invalid blob key")
+		return "", errs.NewUnexpectedError("Unexpected server-side error")
+	}
+
+	return filepath.Join(r.rootDir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net"
+	"os"
+	"strings"
+)
+
+// TrustedProxies are the networks of the reverse proxies (e.g. the load balancer of the hosting provider) whose
+// forwarding headers are believed. Requests from anywhere else are taken to come straight from the client, so that
+// clients cannot choose their own IP address by sending these headers.
+type TrustedProxies []*net.IPNet
+
+// NewTrustedProxies parses the comma-separated CIDRs or IP addresses given in the TRUSTED_PROXIES environment
+// variable. No proxies are trusted if it is not set.
+func NewTrustedProxies() TrustedProxies {
+	proxies := make(TrustedProxies, 0)
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		network, err := ParseCidr(entry)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("Environment variable TRUSTED_PROXIES has an invalid entry %s", entry))
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies
+}
+
+// ParseCidr parses the given network in CIDR notation, or a single IP address as a network with only that address.
+func ParseCidr(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %s", s)
+		}
+		bits := 8 * net.IPv6len
+		if ipv4 := ip.To4(); ipv4 != nil {
+			ip, bits = ipv4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(s)
+	return network, err
+}
+
+// Contains checks whether the given IP address belongs to one of the trusted proxies.
+func (p TrustedProxies) Contains(ip net.IP) bool {
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveClientIp returns the IP address of the client that made a request received from the given remote address,
+// with the given Forwarded (RFC 7239) and X-Forwarded-For header values. The forwarding chain is only followed while
+// the hops are trusted proxies, walking back from the remote address: the first untrusted hop is the client, since
+// anything before it could have been made up. Forwarded is preferred over X-Forwarded-For if both are present.
+func (p TrustedProxies) ResolveClientIp(remoteAddr string, forwarded []string, xForwardedFor []string) string {
+	clientIp := parseIpWithPort(remoteAddr)
+	if clientIp == nil {
+		return remoteAddr
+	}
+	if !p.Contains(clientIp) {
+		return clientIp.String()
+	}
+
+	chain := parseForwardedFor(forwarded)
+	if len(forwarded) == 0 {
+		chain = parseXForwardedFor(xForwardedFor)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		hop := parseIpWithPort(chain[i])
+		if hop == nil { //e.g. an obfuscated or unknown node, so the last known hop is as close as it gets
+			break
+		}
+		clientIp = hop
+		if !p.Contains(hop) {
+			break
+		}
+	}
+	return clientIp.String()
+}
+
+// parseForwardedFor returns the for= parameters of the elements of the given Forwarded header values, in order.
+func parseForwardedFor(values []string) []string {
+	chain := make([]string, 0)
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				name, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(name, "for") {
+					chain = append(chain, strings.Trim(val, `"`))
+				}
+			}
+		}
+	}
+	return chain
+}
+
+// parseXForwardedFor returns the addresses in the given X-Forwarded-For header values, in order.
+func parseXForwardedFor(values []string) []string {
+	chain := make([]string, 0)
+	for _, value := range values {
+		for _, addr := range strings.Split(value, ",") {
+			chain = append(chain, strings.TrimSpace(addr))
+		}
+	}
+	return chain
+}
+
+// parseIpWithPort parses the given IP address, which may have a port and, for IPv6, be in brackets. It returns nil if
+// the address is not an IP address.
+func parseIpWithPort(addr string) net.IP {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	return net.ParseIP(addr)
+}
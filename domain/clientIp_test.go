@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"testing"
+)
+
+func TestParseCidr(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{"ipv4 network", "10.0.0.0/8", "10.0.0.0/8", false},
+		{"ipv4 network with host bits", "10.1.2.3/8", "10.0.0.0/8", false},
+		{"single ipv4 address", "192.0.2.1", "192.0.2.1/32", false},
+		{"ipv4-mapped ipv6 address", "::ffff:192.0.2.1", "192.0.2.1/32", false},
+		{"single ipv6 address", "2001:db8::1", "2001:db8::1/128", false},
+		{"ipv6 network", "2001:db8::/32", "2001:db8::/32", false},
+		{"prefix too long", "10.0.0.0/33", "", true},
+		{"not an address", "localhost", "", true},
+		{"empty", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCidr(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCidr(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("ParseCidr(%q) = %s, want %s", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveClientIp(t *testing.T) {
+	proxies := make(TrustedProxies, 0)
+	for _, s := range []string{"10.0.0.0/8", "::1"} {
+		network, err := ParseCidr(s)
+		if err != nil {
+			t.Fatalf("ParseCidr(%q) error = %v", s, err)
+		}
+		proxies = append(proxies, network)
+	}
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		forwarded     []string
+		xForwardedFor []string
+		want          string
+	}{
+		{"no proxy", "198.51.100.7:5000", nil, nil, "198.51.100.7"},
+		{"headers from untrusted remote ignored", "203.0.113.5:5000", nil, []string{"198.51.100.7"}, "203.0.113.5"},
+		{"trusted proxy without headers", "10.0.0.1:5000", nil, nil, "10.0.0.1"},
+		{"remote address without port", "10.0.0.1", nil, []string{"198.51.100.7"}, "198.51.100.7"},
+		{"x-forwarded-for single hop", "10.0.0.1:5000", nil, []string{"198.51.100.7"}, "198.51.100.7"},
+		{"x-forwarded-for through trusted hops", "10.0.0.1:5000", nil, []string{"198.51.100.7, 10.0.0.2"},
+			"198.51.100.7"},
+		{"x-forwarded-for spoofed by client", "10.0.0.1:5000", nil, []string{"6.6.6.6, 198.51.100.7, 10.0.0.2"},
+			"198.51.100.7"},
+		{"x-forwarded-for in several headers", "10.0.0.1:5000", nil, []string{"6.6.6.6", "198.51.100.7, 10.0.0.2"},
+			"198.51.100.7"},
+		{"x-forwarded-for all trusted", "10.0.0.1:5000", nil, []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"x-forwarded-for with garbage hop", "10.0.0.1:5000", nil, []string{"198.51.100.7, not-an-ip"}, "10.0.0.1"},
+		{"forwarded preferred over x-forwarded-for", "10.0.0.1:5000", []string{"for=198.51.100.8"},
+			[]string{"198.51.100.7"}, "198.51.100.8"},
+		{"forwarded with other parameters", "10.0.0.1:5000",
+			[]string{"for=198.51.100.9;proto=https;by=10.0.0.1, for=10.0.0.5"}, nil, "198.51.100.9"},
+		{"forwarded quoted ipv6 with port", "10.0.0.1:5000", []string{`for="[2001:db8:cafe::17]:4711"`}, nil,
+			"2001:db8:cafe::17"},
+		{"forwarded case-insensitive parameter", "10.0.0.1:5000", []string{"For=198.51.100.9"}, nil, "198.51.100.9"},
+		{"forwarded obfuscated hop", "10.0.0.1:5000", []string{"for=198.51.100.9, for=_hidden, for=10.0.0.5"}, nil,
+			"10.0.0.5"},
+		{"forwarded unknown hop", "10.0.0.1:5000", []string{"for=unknown"}, nil, "10.0.0.1"},
+		{"forwarded without for", "10.0.0.1:5000", []string{"proto=https"}, []string{"198.51.100.7"}, "10.0.0.1"},
+		{"ipv6 trusted proxy", "[::1]:443", nil, []string{"2001:db8::5"}, "2001:db8::5"},
+		{"invalid remote address", "pipe", nil, []string{"198.51.100.7"}, "pipe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := proxies.ResolveClientIp(tt.remoteAddr, tt.forwarded, tt.xForwardedFor)
+			if got != tt.want {
+				t.Errorf("ResolveClientIp(%q, %q, %q) = %s, want %s", tt.remoteAddr, tt.forwarded, tt.xForwardedFor,
+					got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"github.com/aliciatay-zls/banking-lib/errs"
 	"github.com/aliciatay-zls/banking-lib/logger"
 	"golang.org/x/crypto/bcrypt"
@@ -24,3 +26,14 @@ func IsHashGivenPassword(hashedPw string, pw string) bool {
 	}
 	return true
 }
+
+// GenerateRandomId returns a random 128-bit identifier in hex form, e.g. for use as the unique ID (jti) of a token.
+func GenerateRandomId() (string, *errs.AppError) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		logger.Error("Error while generating random id: " + err.Error())
+		return "", errs.NewUnexpectedError("Unexpected server-side error")
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"github.com/aliciatay-zls/banking-auth/dto"
 	"github.com/aliciatay-zls/banking-lib/errs"
 	"github.com/aliciatay-zls/banking-lib/logger"
 	"github.com/golang-jwt/jwt/v5"
@@ -13,6 +14,9 @@ const OneTimeTokenDuration = time.Hour
 const TokenTypeRefresh = "refresh token"
 const TokenTypeAccess = "access token"
 const TokenTypeOneTime = "OTT"
+const TokenTypeEmailChange = "email change token"
+const TokenTypeDataExport = "data export token"
+const TokenTypeInvitation = "invitation token"
 
 type AccessTokenClaims struct {
 	jwt.RegisteredClaims
@@ -29,12 +33,41 @@ type RefreshTokenClaims struct {
 	CustomerId string `json:"cid"`
 }
 
+// OneTimeTokenClaims uses the registered ID (jti) claim to identify the token in the store so that it can only be used
+// once.
 type OneTimeTokenClaims struct {
 	jwt.RegisteredClaims
 	Email          string `json:"email"`
 	DateRegistered string `json:"created_on"`
 }
 
+// EmailChangeTokenClaims are for the one-time token in the link sent to a new email address to verify it before
+// a customer's email is changed to it. The registered ID (jti) claim identifies the token in the store.
+type EmailChangeTokenClaims struct {
+	jwt.RegisteredClaims
+	TokenType  string `json:"token_type"`
+	CustomerId string `json:"cid"`
+	OldEmail   string `json:"old_email"`
+	NewEmail   string `json:"new_email"`
+}
+
+// DataExportTokenClaims are for the one-time token in the link sent to a customer to download an export of their
+// data. The registered ID (jti) claim identifies the token in the store.
+type DataExportTokenClaims struct {
+	jwt.RegisteredClaims
+	TokenType string `json:"token_type"`
+	ExportId  string `json:"export_id"`
+}
+
+// InvitationTokenClaims are for the one-time token in the link sent to invite a new admin. The registered ID (jti)
+// claim identifies the token in the store.
+type InvitationTokenClaims struct {
+	jwt.RegisteredClaims
+	TokenType    string `json:"token_type"`
+	InvitationId string `json:"invitation_id"`
+	Email        string `json:"email"`
+}
+
 // Validate checks the access token's expiry date and whether the role corresponds with the customer ID.
 // The token must be expired to be considered valid during the process of refreshing it (wantExpired is true).
 // Otherwise, it should not be expired.
@@ -82,6 +115,51 @@ func (c *OneTimeTokenClaims) CheckExpiry() *errs.AppError {
 	return nil
 }
 
+// Validate checks the email change token's expiry date and token type.
+func (c *EmailChangeTokenClaims) Validate() *errs.AppError {
+	if !c.ExpiresAt.After(time.Now().UTC()) {
+		logger.Error("Expired email change token")
+		return errs.NewAuthenticationError("expired email change token")
+	}
+
+	if c.TokenType != TokenTypeEmailChange || c.CustomerId == "" {
+		logger.Error("Invalid email change token")
+		return errs.NewAuthenticationError("Invalid email change token")
+	}
+
+	return nil
+}
+
+// Validate checks the data export token's expiry date and token type.
+func (c *DataExportTokenClaims) Validate() *errs.AppError {
+	if !c.ExpiresAt.After(time.Now().UTC()) {
+		logger.Error("Expired data export token")
+		return errs.NewAuthenticationError("expired data export token")
+	}
+
+	if c.TokenType != TokenTypeDataExport || c.ExportId == "" {
+		logger.Error("Invalid data export token")
+		return errs.NewAuthenticationError("Invalid data export token")
+	}
+
+	return nil
+}
+
+// Validate checks the invitation token's expiry date and token type.
+func (c *InvitationTokenClaims) Validate() *errs.AppError {
+	if !c.ExpiresAt.After(time.Now().UTC()) {
+		logger.Error("Expired invitation token")
+		return errs.NewAuthenticationError("expired invitation token")
+	}
+
+	if c.TokenType != TokenTypeInvitation || c.InvitationId == "" {
+		logger.Error("Invalid invitation token")
+		return errs.NewAuthenticationError("Invalid invitation token")
+	}
+
+	return nil
+}
+
 // isRoleValid is similar to auth.go#IsRoleValid.
 func isRoleValid(role string, cid string) bool {
 	if role != RoleUser && role != RoleAdmin {
@@ -139,6 +217,14 @@ func (c *RefreshTokenClaims) AsAccessTokenClaims() AccessTokenClaims {
 	}
 }
 
+func (c *AccessTokenClaims) ToIdentityDTO() *dto.Identity {
+	return &dto.Identity{
+		Username:   c.Username,
+		Role:       c.Role,
+		CustomerId: c.CustomerId,
+	}
+}
+
 func ArePrivateClaimsSame(accessClaims *AccessTokenClaims, refreshClaims *RefreshTokenClaims) *errs.AppError {
 	if accessClaims.Username != refreshClaims.Username ||
 		accessClaims.Role != refreshClaims.Role ||
@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"database/sql"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/golang-jwt/jwt/v5"
+	"strings"
+	"time"
+)
+
+type Customer struct { //business/domain object
+	Id          string `db:"customer_id"`
+	Name        string
+	DateOfBirth string `db:"date_of_birth"` //yyyy-mm-dd
+	Email       string
+	Country     string
+	CountryCode sql.NullString `db:"country_code"` //from the customer's registration, if recorded
+	Zipcode     string
+	Status      string
+	Locale      string //from the customer's registration, DefaultLocale if not recorded
+}
+
+// ProfileChange is one entry in the audit trail of changes to a Customer's profile.
+type ProfileChange struct {
+	CustomerId  string `db:"customer_id"`
+	Field       string
+	OldValue    string `db:"old_value"`
+	NewValue    string `db:"new_value"`
+	ChangedBy   string `db:"changed_by"` //username of the client who made the change
+	DateChanged string `db:"changed_on"`
+}
+
+// GetEmailChangeTokenClaims creates the claims for a new one-time token to verify the given new email before the
+// Customer's email is changed to it, with a random unique ID so that the token can be tracked and used only once.
+func (c Customer) GetEmailChangeTokenClaims(newEmail string) (*EmailChangeTokenClaims, *errs.AppError) {
+	tokenId, err := GenerateRandomId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &EmailChangeTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenId,
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(OneTimeTokenDuration)),
+		},
+		TokenType:  TokenTypeEmailChange,
+		CustomerId: c.Id,
+		OldEmail:   c.Email,
+		NewEmail:   newEmail,
+	}, nil
+}
+
+// ApplyProfileUpdate updates the Customer with the non-empty fields of the given request and returns the changes
+// made, to be recorded by the given client at the given time. Fields that are given but unchanged are left out.
+func (c *Customer) ApplyProfileUpdate(req dto.ProfileUpdateRequest, changedBy string, changeTime string) []ProfileChange {
+	changes := make([]ProfileChange, 0)
+	record := func(field string, current *string, value string) {
+		if value == "" || value == *current {
+			return
+		}
+		changes = append(changes, ProfileChange{
+			CustomerId:  c.Id,
+			Field:       field,
+			OldValue:    *current,
+			NewValue:    value,
+			ChangedBy:   changedBy,
+			DateChanged: changeTime,
+		})
+		*current = value
+	}
+
+	if req.FirstName != "" {
+		record("name", &c.Name, strings.Join([]string{req.FirstName, req.LastName}, " "))
+	}
+	record("date_of_birth", &c.DateOfBirth, req.DateOfBirth)
+	if req.CountryCode != "" {
+		record("country", &c.Country, formValidator.GetCountryFrom(req.CountryCode))
+		c.CountryCode = sql.NullString{String: req.CountryCode, Valid: true}
+	}
+	record("zipcode", &c.Zipcode, req.Zipcode)
+	if req.Locale != "" {
+		record("locale", &c.Locale, NormaliseLocale(req.Locale))
+	}
+
+	return changes
+}
+
+func (c Customer) ToProfileDTO() *dto.ProfileResponse {
+	return &dto.ProfileResponse{
+		CustomerId:  c.Id,
+		Name:        c.Name,
+		DateOfBirth: c.DateOfBirth,
+		Email:       c.Email,
+		Country:     c.Country,
+		CountryCode: c.CountryCode.String,
+		Zipcode:     c.Zipcode,
+		Locale:      c.Locale,
+	}
+}
+
+func (p ProfileChange) ToDTO() dto.ProfileChangeResponse {
+	return dto.ProfileChangeResponse{
+		Field:       p.Field,
+		OldValue:    p.OldValue,
+		NewValue:    p.NewValue,
+		ChangedBy:   p.ChangedBy,
+		DateChanged: p.DateChanged,
+	}
+}
@@ -0,0 +1,184 @@
+package domain
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type CustomerRepository interface { //repo (secondary port)
+	FindById(string) (*Customer, *errs.AppError)
+	ChangeEmail(*EmailChangeTokenClaims) *errs.AppError
+	UpdateProfile(string, dto.ProfileUpdateRequest, string, string) (*Customer, *errs.AppError)
+	FindProfileChanges(string) ([]ProfileChange, *errs.AppError)
+}
+
+// findCustomerSql also gets the country code and locale recorded in the customer's registration, as customers only
+// store the country name.
+const findCustomerSql = `SELECT c.customer_id, c.name, c.date_of_birth, c.email, c.country, r.country_code, c.zipcode, 
+	c.status, COALESCE(r.locale, '` + DefaultLocale + `') AS locale 
+	FROM customers c LEFT JOIN registrations r ON r.customer_id = c.customer_id WHERE c.customer_id = ?`
+
+type CustomerRepositoryDb struct { //DB (adapter)
+	client *sqlx.DB
+}
+
+func NewCustomerRepositoryDb(dbClient *sqlx.DB) CustomerRepositoryDb {
+	return CustomerRepositoryDb{dbClient}
+}
+
+// FindById retrieves the Customer with the given customer ID. It is expected to exist, so an error is returned if it
+// does not exist.
+func (d CustomerRepositoryDb) FindById(id string) (*Customer, *errs.AppError) {
+	var customer Customer
+	if err := d.client.Get(&customer, findCustomerSql, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("The given customer does not exist")
+			return nil, errs.NewNotFoundError("Customer not found")
+		}
+		logger.Error("Error while retrieving customer: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return &customer, nil
+}
+
+// ChangeEmail changes the email of the Customer in the given claims from the old to the new email in a single db
+// transaction: the customer row is locked, the customer's email must still be the old email and the new email must
+// still be unused (as in RegistrationRepositoryDb.IsEmailUsed), then the one-time token of the claims is used up and
+// the email is updated in all records of the customer.
+func (d CustomerRepositoryDb) ChangeEmail(claims *EmailChangeTokenClaims) *errs.AppError {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for changing email: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	var currentEmail string
+	if err = tx.Get(&currentEmail, "SELECT email FROM customers WHERE customer_id = ? FOR UPDATE", claims.CustomerId); err != nil {
+		rollbackTx(tx, "locking of customer")
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("The given customer does not exist")
+			return errs.NewNotFoundError("Customer not found")
+		}
+		logger.Error("Error while locking customer: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if currentEmail != claims.OldEmail {
+		rollbackTx(tx, "locking of customer whose email already changed")
+		logger.Error("Email of customer already changed since email change was requested")
+		return errs.NewConflictError("Email already changed")
+	}
+
+	if appErr := isEmailUsed(tx, claims.NewEmail); appErr != nil {
+		rollbackTx(tx, "checking of new email")
+		return appErr
+	}
+
+	if appErr := markOneTimeTokenUsed(tx, claims.ID); appErr != nil {
+		rollbackTx(tx, "using up of email change token")
+		return appErr
+	}
+
+	updates := []struct {
+		Description string
+		Sql         string
+		Key         string
+	}{
+		{"customer", "UPDATE customers SET email = ? WHERE customer_id = ?", claims.CustomerId},
+		{"registration", "UPDATE registrations SET email = ? WHERE customer_id = ?", claims.CustomerId},
+		{"KYC documents", "UPDATE kyc_documents SET email = ? WHERE email = ?", claims.OldEmail},
+	}
+	for _, u := range updates {
+		if _, err = tx.Exec(u.Sql, claims.NewEmail, u.Key); err != nil {
+			logger.Error("Error while changing email of " + u.Description + ": " + err.Error())
+			rollbackTx(tx, "changing of email")
+			return errs.NewUnexpectedError("Unexpected database error")
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for changing email: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// UpdateProfile updates the profile of the Customer with the given customer ID using the given request, on behalf of
+// the given client at the given time, in a single db transaction: the customer row is locked, the changes are
+// applied to both the customer and their registration, and each changed field is recorded in the audit trail.
+// It returns the updated Customer.
+func (d CustomerRepositoryDb) UpdateProfile(id string, req dto.ProfileUpdateRequest, changedBy string, changeTime string) (*Customer, *errs.AppError) {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for updating profile: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	var customer Customer
+	if err = tx.Get(&customer, findCustomerSql+" FOR UPDATE", id); err != nil {
+		rollbackTx(tx, "locking of customer")
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("The given customer does not exist")
+			return nil, errs.NewNotFoundError("Customer not found")
+		}
+		logger.Error("Error while locking customer: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	changes := customer.ApplyProfileUpdate(req, changedBy, changeTime)
+	if len(changes) == 0 {
+		rollbackTx(tx, "updating of unchanged profile")
+		return &customer, nil
+	}
+
+	updateCustomerSql := "UPDATE customers SET name = ?, date_of_birth = ?, country = ?, zipcode = ? WHERE customer_id = ?"
+	if _, err = tx.Exec(updateCustomerSql, customer.Name, customer.DateOfBirth, customer.Country, customer.Zipcode, id); err != nil {
+		logger.Error("Error while updating profile of customer: " + err.Error())
+		rollbackTx(tx, "updating of profile")
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	updateRegistrationSql := `UPDATE registrations SET name = ?, date_of_birth = ?, country = ?, country_code = ?, zipcode = ?, 
+		locale = ? WHERE customer_id = ?`
+	if _, err = tx.Exec(updateRegistrationSql, customer.Name, customer.DateOfBirth, customer.Country, customer.CountryCode,
+		customer.Zipcode, customer.Locale, id); err != nil {
+		logger.Error("Error while updating profile in registration: " + err.Error())
+		rollbackTx(tx, "updating of profile")
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	insertChangeSql := `INSERT INTO profile_changes (customer_id, field, old_value, new_value, changed_by, changed_on) 
+		VALUES (:customer_id, :field, :old_value, :new_value, :changed_by, :changed_on)`
+	if _, err = tx.NamedExec(insertChangeSql, changes); err != nil {
+		logger.Error("Error while recording profile changes: " + err.Error())
+		rollbackTx(tx, "updating of profile")
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for updating profile: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return &customer, nil
+}
+
+// FindProfileChanges retrieves the audit trail of changes to the profile of the Customer with the given customer ID,
+// latest first.
+func (d CustomerRepositoryDb) FindProfileChanges(id string) ([]ProfileChange, *errs.AppError) {
+	changes := make([]ProfileChange, 0)
+	findSql := `SELECT customer_id, field, old_value, new_value, changed_by, changed_on FROM profile_changes 
+		WHERE customer_id = ? ORDER BY changed_on DESC, change_id DESC`
+	if err := d.client.Select(&changes, findSql, id); err != nil {
+		logger.Error("Error while retrieving profile changes: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return changes, nil
+}
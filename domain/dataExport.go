@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"database/sql"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+const DataExportStatusPending = "pending"
+const DataExportStatusReady = "ready"
+const DataExportStatusFailed = "failed"
+const DataExportStatusExpired = "expired"
+
+// DataExport is a request by a customer for an archive of the data held about them, which is generated in the
+// background and kept in the blob store under StorageKey until the download link expires.
+type DataExport struct { //business/domain object
+	ExportId      string         `db:"export_id"`
+	CustomerId    string         `db:"customer_id"`
+	Username      string         `db:"username"`
+	Status        string         `db:"status"`
+	StorageKey    string         `db:"storage_key"`
+	DateRequested string         `db:"requested_on"`
+	DateReady     sql.NullString `db:"ready_on"`
+}
+
+// NewDataExport creates a new pending DataExport for the given customer, identified by a random unique ID.
+func NewDataExport(customerId string, username string) (*DataExport, *errs.AppError) {
+	exportId, err := GenerateRandomId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataExport{
+		ExportId:      exportId,
+		CustomerId:    customerId,
+		Username:      username,
+		Status:        DataExportStatusPending,
+		StorageKey:    "exports/" + exportId + ".json",
+		DateRequested: time.Now().UTC().Format(FormatDateTime),
+	}, nil
+}
+
+// GetDataExportTokenClaims creates the claims for a new one-time token to download the DataExport, with a random
+// unique ID so that the token can be tracked and used only once.
+func (e DataExport) GetDataExportTokenClaims() (*DataExportTokenClaims, *errs.AppError) {
+	tokenId, err := GenerateRandomId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataExportTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenId,
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(OneTimeTokenDuration)),
+		},
+		TokenType: TokenTypeDataExport,
+		ExportId:  e.ExportId,
+	}, nil
+}
+
+// CheckDownloadable ensures that the archive of the DataExport has been generated and not yet removed.
+func (e DataExport) CheckDownloadable() *errs.AppError {
+	if e.Status != DataExportStatusReady {
+		logger.Error("Data export is not ready for download: " + e.Status)
+		return errs.NewNotFoundError("Data export not available")
+	}
+	return nil
+}
+
+func (e DataExport) ToDTO() *dto.DataExportResponse {
+	return &dto.DataExportResponse{
+		ExportId:      e.ExportId,
+		Status:        e.Status,
+		DateRequested: e.DateRequested,
+	}
+}
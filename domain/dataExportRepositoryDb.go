@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type DataExportRepository interface { //repo (secondary port)
+	Save(DataExport) *errs.AppError
+	Find(string) (*DataExport, *errs.AppError)
+	UpdateStatus(string, string, string) *errs.AppError
+	FindExpired(string) ([]DataExport, *errs.AppError)
+}
+
+type DataExportRepositoryDb struct { //DB (adapter)
+	client *sqlx.DB
+}
+
+func NewDataExportRepositoryDb(dbClient *sqlx.DB) DataExportRepositoryDb {
+	return DataExportRepositoryDb{dbClient}
+}
+
+// Save records the given pending DataExport, unless the customer already has one pending.
+func (d DataExportRepositoryDb) Save(export DataExport) *errs.AppError {
+	insertSql := `INSERT INTO data_exports (export_id, customer_id, username, status, storage_key, requested_on) 
+		SELECT :export_id, :customer_id, :username, :status, :storage_key, :requested_on FROM DUAL 
+		WHERE NOT EXISTS (SELECT 1 FROM data_exports WHERE customer_id = :customer_id AND status = 'pending')`
+	result, err := d.client.NamedExec(insertSql, export)
+	if err != nil {
+		logger.Error("Error while saving data export: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	rowsInserted, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Error while checking that data export was saved: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	if rowsInserted != 1 {
+		logger.Error("Data export already pending for customer")
+		return errs.NewConflictError("Data export already in progress")
+	}
+
+	return nil
+}
+
+// Find retrieves the DataExport with the given export ID.
+func (d DataExportRepositoryDb) Find(exportId string) (*DataExport, *errs.AppError) {
+	var export DataExport
+	if err := d.client.Get(&export, "SELECT * FROM data_exports WHERE export_id = ?", exportId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("The given data export does not exist")
+			return nil, errs.NewNotFoundError("Data export not available")
+		}
+		logger.Error("Error while retrieving data export: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return &export, nil
+}
+
+// UpdateStatus sets the status of the DataExport with the given export ID, recording the given time as the time it
+// became ready if the new status is ready.
+func (d DataExportRepositoryDb) UpdateStatus(exportId string, status string, updateTime string) *errs.AppError {
+	updateSql := "UPDATE data_exports SET status = ?, ready_on = IF(? = 'ready', ?, ready_on) WHERE export_id = ?"
+	if _, err := d.client.Exec(updateSql, status, status, updateTime, exportId); err != nil {
+		logger.Error("Error while updating status of data export: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// FindExpired retrieves the data exports whose archive was ready on or before the given cutoff time, or whose
+// generation failed, so that their archives can be removed.
+func (d DataExportRepositoryDb) FindExpired(cutoff string) ([]DataExport, *errs.AppError) {
+	exports := make([]DataExport, 0)
+	findSql := "SELECT * FROM data_exports WHERE (status = 'ready' AND ready_on <= ?) OR status = 'failed'"
+	if err := d.client.Select(&exports, findSql, cutoff); err != nil {
+		logger.Error("Error while retrieving expired data exports: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return exports, nil
+}
@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// rollbackTx rolls back the given transaction, logging any error using the given description of what is rolled back.
+func rollbackTx(tx *sqlx.Tx, description string) {
+	if err := tx.Rollback(); err != nil {
+		logger.Error(fmt.Sprintf("Error while rolling back %s: %s", description, err.Error()))
+	}
+}
@@ -0,0 +1,263 @@
+package domain
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"os"
+	"strings"
+	"time"
+)
+
+const DkimAlgorithmRsaSha256 = "rsa-sha256"
+const DkimAlgorithmEd25519Sha256 = "ed25519-sha256" //RFC 8463
+const DkimCanonicalizationSimple = "simple"
+const DkimCanonicalizationRelaxed = "relaxed"
+const DefaultDkimCanonicalization = DkimCanonicalizationRelaxed + "/" + DkimCanonicalizationRelaxed
+
+// DkimSignedHeaders are the headers signed if present, covering everything the recipient sees and how it is displayed.
+var DkimSignedHeaders = []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type"}
+
+// DkimSigner adds a DKIM-Signature header (RFC 6376) to outgoing messages, so that receiving servers can check that
+// they were sent on behalf of the domain and not altered on the way. The public key must be published in DNS as a TXT
+// record at <selector>._domainkey.<domain>.
+type DkimSigner struct {
+	domain                 string
+	selector               string
+	key                    crypto.Signer
+	algorithm              string
+	headerCanonicalization string
+	bodyCanonicalization   string
+}
+
+// NewDkimSigner creates a DkimSigner if the DKIM_PRIVATE_KEY_PATH environment variable is set, otherwise it returns
+// nil and messages are not signed. The key is a PEM-encoded RSA (PKCS #1 or #8) or Ed25519 (PKCS #8) private key,
+// which determines the algorithm. DKIM_SELECTOR must also be set, while DKIM_DOMAIN defaults to the domain of
+// MAIL_SENDER and DKIM_CANONICALIZATION (<header>/<body>, each simple or relaxed) to relaxed/relaxed.
+func NewDkimSigner() *DkimSigner {
+	keyPath := os.Getenv("DKIM_PRIVATE_KEY_PATH")
+	if keyPath == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(keyPath)
+	if err != nil {
+		logger.Fatal("Error while reading DKIM private key: " + err.Error())
+	}
+	key, algorithm, err := parseDkimPrivateKey(content)
+	if err != nil {
+		logger.Fatal("Error while parsing DKIM private key: " + err.Error())
+	}
+
+	selector := os.Getenv("DKIM_SELECTOR")
+	if selector == "" {
+		logger.Fatal("Environment variable DKIM_SELECTOR was not defined")
+	}
+	domain := os.Getenv("DKIM_DOMAIN")
+	if domain == "" {
+		domain = getEmailDomain(os.Getenv("MAIL_SENDER"))
+	}
+	canonicalization := os.Getenv("DKIM_CANONICALIZATION")
+	if canonicalization == "" {
+		canonicalization = DefaultDkimCanonicalization
+	}
+	headerCanon, bodyCanon, _ := strings.Cut(canonicalization, "/")
+	if !isDkimCanonicalization(headerCanon) || !isDkimCanonicalization(bodyCanon) {
+		logger.Fatal("Environment variable DKIM_CANONICALIZATION is not a valid canonicalization")
+	}
+
+	return &DkimSigner{
+		domain:                 domain,
+		selector:               selector,
+		key:                    key,
+		algorithm:              algorithm,
+		headerCanonicalization: headerCanon,
+		bodyCanonicalization:   bodyCanon,
+	}
+}
+
+func isDkimCanonicalization(c string) bool {
+	return c == DkimCanonicalizationSimple || c == DkimCanonicalizationRelaxed
+}
+
+// parseDkimPrivateKey parses the given PEM-encoded private key, returning it along with the DKIM algorithm to use.
+func parseDkimPrivateKey(content []byte) (crypto.Signer, string, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, "", errors.New("no PEM block found")
+	}
+
+	var key any
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, DkimAlgorithmRsaSha256, nil
+	case ed25519.PrivateKey:
+		return k, DkimAlgorithmEd25519Sha256, nil
+	default:
+		return nil, "", errors.New("key must be an RSA or Ed25519 private key")
+	}
+}
+
+// Sign returns the given message with a DKIM-Signature header added at the top, signing the DkimSignedHeaders present
+// in it and its body, with the signing time set to the given time.
+func (s DkimSigner) Sign(msg []byte, now time.Time) ([]byte, error) {
+	rawHeaders, body, found := bytes.Cut(msg, []byte("\r\n\r\n"))
+	if !found {
+		return nil, errors.New("message has no body")
+	}
+	headers := splitDkimHeaders(string(rawHeaders) + "\r\n")
+
+	var canonBody string
+	if s.bodyCanonicalization == DkimCanonicalizationRelaxed {
+		canonBody = canonicalizeBodyRelaxed(string(body))
+	} else {
+		canonBody = canonicalizeBodySimple(string(body))
+	}
+	bodyHash := sha256.Sum256([]byte(canonBody))
+
+	//headers are signed from the bottom up, as later instances of a header are the ones added last
+	var signedNames []string
+	var hashInput strings.Builder
+	used := make(map[int]bool)
+	for _, name := range DkimSignedHeaders {
+		for i := len(headers) - 1; i >= 0; i-- {
+			if used[i] || !strings.EqualFold(getDkimHeaderName(headers[i]), name) {
+				continue
+			}
+			used[i] = true
+			signedNames = append(signedNames, strings.ToLower(name))
+			hashInput.WriteString(s.canonicalizeHeader(headers[i]))
+			break
+		}
+	}
+
+	sigHeader := fmt.Sprintf("DKIM-Signature: v=1; a=%s; c=%s/%s; d=%s; s=%s;\r\n\tt=%d; h=%s;\r\n\tbh=%s;\r\n\tb=",
+		s.algorithm, s.headerCanonicalization, s.bodyCanonicalization, s.domain, s.selector, now.Unix(),
+		strings.Join(signedNames, ":"), base64.StdEncoding.EncodeToString(bodyHash[:]))
+	//the DKIM-Signature header itself is signed with an empty b= tag and without its trailing CRLF
+	hashInput.WriteString(strings.TrimSuffix(s.canonicalizeHeader(sigHeader+"\r\n"), "\r\n"))
+	hash := sha256.Sum256([]byte(hashInput.String()))
+
+	var signature []byte
+	var err error
+	if s.algorithm == DkimAlgorithmEd25519Sha256 {
+		//RFC 8463: the SHA-256 hash is signed with PureEdDSA, so it is passed as the message itself
+		signature, err = s.key.Sign(rand.Reader, hash[:], crypto.Hash(0))
+	} else {
+		signature, err = s.key.Sign(rand.Reader, hash[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signed := sigHeader + base64.StdEncoding.EncodeToString(signature) + "\r\n"
+	return append([]byte(signed), msg...), nil
+}
+
+func (s DkimSigner) canonicalizeHeader(header string) string {
+	if s.headerCanonicalization == DkimCanonicalizationRelaxed {
+		return canonicalizeHeaderRelaxed(header)
+	}
+	return header
+}
+
+// splitDkimHeaders splits the given header section, ending with CRLF, into its headers, each including any folded
+// continuation lines and its trailing CRLF.
+func splitDkimHeaders(rawHeaders string) []string {
+	var headers []string
+	for _, line := range strings.SplitAfter(rawHeaders, "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(headers) > 0 {
+			headers[len(headers)-1] += line
+		} else {
+			headers = append(headers, line)
+		}
+	}
+	return headers
+}
+
+func getDkimHeaderName(header string) string {
+	name, _, _ := strings.Cut(header, ":")
+	return strings.TrimRight(name, " \t")
+}
+
+// canonicalizeHeaderRelaxed applies the "relaxed" header canonicalization (RFC 6376 section 3.4.2) to the given
+// header: the name is lowercased, the value unfolded, runs of whitespace reduced to a single space, and whitespace at
+// the ends of the value and around the colon removed.
+func canonicalizeHeaderRelaxed(header string) string {
+	name, value, _ := strings.Cut(header, ":")
+	name = strings.ToLower(strings.TrimRight(name, " \t"))
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = strings.Join(strings.FieldsFunc(value, isDkimWhitespace), " ")
+	return name + ":" + value + "\r\n"
+}
+
+// canonicalizeBodySimple applies the "simple" body canonicalization (RFC 6376 section 3.4.3) to the given body:
+// empty lines at the end are removed, and an empty body becomes a single CRLF.
+func canonicalizeBodySimple(body string) string {
+	return trimDkimEmptyLines(body) + "\r\n"
+}
+
+// canonicalizeBodyRelaxed applies the "relaxed" body canonicalization (RFC 6376 section 3.4.4) to the given body:
+// whitespace at the end of lines is removed, runs of whitespace within lines are reduced to a single space, and empty
+// lines at the end are removed. An empty body stays empty.
+func canonicalizeBodyRelaxed(body string) string {
+	lines := strings.Split(body, "\r\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		var b strings.Builder
+		isPrevWhitespace := false
+		for _, r := range line {
+			if isDkimWhitespace(r) {
+				if !isPrevWhitespace {
+					b.WriteByte(' ')
+				}
+				isPrevWhitespace = true
+				continue
+			}
+			isPrevWhitespace = false
+			b.WriteRune(r)
+		}
+		lines[i] = b.String()
+	}
+
+	canon := trimDkimEmptyLines(strings.Join(lines, "\r\n"))
+	if canon == "" {
+		return ""
+	}
+	return canon + "\r\n"
+}
+
+// trimDkimEmptyLines removes all CRLFs at the end of the given body, leaving any other trailing characters such as a
+// lone CR in place.
+func trimDkimEmptyLines(body string) string {
+	for strings.HasSuffix(body, "\r\n") {
+		body = strings.TrimSuffix(body, "\r\n")
+	}
+	return body
+}
+
+func isDkimWhitespace(r rune) bool {
+	return r == ' ' || r == '\t'
+}
@@ -0,0 +1,274 @@
+package domain
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6376 section 3.4.5
+const rfc6376Headers = "A: X\r\nB : Y\t\r\n\tZ  \r\n"
+const rfc6376Body = " C \r\nD \t E\r\n\r\n\r\n"
+
+// RFC 8463 appendix A
+const rfc8463Seed = "nWGxne/9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A="
+const rfc8463Message = "From: Joe SixPack <joe@football.example.com>\r\n" +
+	"To: Suzie Q <suzie@shopping.example.net>\r\n" +
+	"Subject: Is dinner ready?\r\n" +
+	"Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)\r\n" +
+	"Message-ID: <20030712040037.46341.5F8J@football.example.com>\r\n" +
+	"\r\n" +
+	"Hi.\r\n" +
+	"\r\n" +
+	"We lost the game.  Are you hungry yet?\r\n" +
+	"\r\n" +
+	"Joe.\r\n"
+const rfc8463SignatureHeader = "DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;\r\n" +
+	" d=football.example.com; i=@football.example.com;\r\n" +
+	" q=dns/txt; s=brisbane; t=1528637909; h=from : to :\r\n" +
+	" subject : date : message-id : from : subject : date;\r\n" +
+	" bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;\r\n" +
+	" b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11BusFa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==\r\n"
+const rfc8463BodyHash = "2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8="
+
+func TestCanonicalizeHeaderRelaxed(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"rfc 6376 A", "A: X\r\n", "a:X\r\n"},
+		{"rfc 6376 B", "B : Y\t\r\n\tZ  \r\n", "b:Y Z\r\n"},
+		{"empty value", "Subject:\r\n", "subject:\r\n"},
+		{"whitespace only value", "Subject: \t \r\n", "subject:\r\n"},
+		{"colon in value", "Date: Fri, 11 Jul 2003 21:00:37\r\n", "date:Fri, 11 Jul 2003 21:00:37\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalizeHeaderRelaxed(tt.header); got != tt.want {
+				t.Errorf("canonicalizeHeaderRelaxed(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeHeaderSimple(t *testing.T) {
+	s := DkimSigner{headerCanonicalization: DkimCanonicalizationSimple}
+	for _, header := range splitDkimHeaders(rfc6376Headers) {
+		if got := s.canonicalizeHeader(header); got != header {
+			t.Errorf("canonicalizeHeader(%q) = %q, want it unchanged", header, got)
+		}
+	}
+}
+
+func TestSplitDkimHeaders(t *testing.T) {
+	got := splitDkimHeaders(rfc6376Headers)
+	want := []string{"A: X\r\n", "B : Y\t\r\n\tZ  \r\n"}
+	if len(got) != len(want) {
+		t.Fatalf("splitDkimHeaders() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("splitDkimHeaders()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCanonicalizeBodySimple(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"rfc 6376", rfc6376Body, " C \r\nD \t E\r\n"},
+		{"empty body", "", "\r\n"},
+		{"only empty lines", "\r\n\r\n", "\r\n"},
+		{"single trailing crlf", "abc\r\n", "abc\r\n"},
+		{"no trailing crlf", "abc", "abc\r\n"},
+		{"trailing lone cr kept", "abc\r", "abc\r\r\n"},
+		{"whitespace line kept", "abc\r\n \r\n", "abc\r\n \r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalizeBodySimple(tt.body); got != tt.want {
+				t.Errorf("canonicalizeBodySimple(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeBodyRelaxed(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"rfc 6376", rfc6376Body, " C\r\nD E\r\n"},
+		{"empty body", "", ""},
+		{"only empty lines", "\r\n\r\n", ""},
+		{"only whitespace lines", " \r\n\t\r\n", ""},
+		{"single trailing crlf", "abc\r\n", "abc\r\n"},
+		{"no trailing crlf", "abc", "abc\r\n"},
+		{"inner empty line kept", "a\r\n\r\nb\r\n", "a\r\n\r\nb\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalizeBodyRelaxed(tt.body); got != tt.want {
+				t.Errorf("canonicalizeBodyRelaxed(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEmptyBodyHashes checks the body hashes of an empty body given in RFC 6376 section 3.4.3 and 3.4.4.
+func TestEmptyBodyHashes(t *testing.T) {
+	tests := []struct {
+		name  string
+		canon string
+		want  string
+	}{
+		{"simple", canonicalizeBodySimple(""), "frcCV1k9oG9oKj3dpUqdJg1PxRT2RSN/XKdLCPjaYaY="},
+		{"relaxed", canonicalizeBodyRelaxed(""), "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash := sha256.Sum256([]byte(tt.canon))
+			if got := base64.StdEncoding.EncodeToString(hash[:]); got != tt.want {
+				t.Errorf("body hash = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRfc8463Signature verifies the example signature of RFC 8463 with the canonicalization used by DkimSigner.
+func TestRfc8463Signature(t *testing.T) {
+	key := getRfc8463Key(t)
+	verifyDkimSignature(t, []byte(rfc8463SignatureHeader+rfc8463Message), key.Public())
+}
+
+func TestSignEd25519(t *testing.T) {
+	key := getRfc8463Key(t)
+	s := DkimSigner{
+		domain:                 "football.example.com",
+		selector:               "brisbane",
+		key:                    key,
+		algorithm:              DkimAlgorithmEd25519Sha256,
+		headerCanonicalization: DkimCanonicalizationRelaxed,
+		bodyCanonicalization:   DkimCanonicalizationRelaxed,
+	}
+
+	signed, err := s.Sign([]byte(rfc8463Message), time.Unix(1528637909, 0))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if tags := verifyDkimSignature(t, signed, key.Public()); tags["bh"] != rfc8463BodyHash {
+		t.Errorf("bh = %s, want %s", tags["bh"], rfc8463BodyHash)
+	}
+}
+
+func TestSignRsaSha256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	for _, canon := range []string{DkimCanonicalizationSimple, DkimCanonicalizationRelaxed} {
+		t.Run(canon, func(t *testing.T) {
+			s := DkimSigner{
+				domain:                 "football.example.com",
+				selector:               "brisbane",
+				key:                    key,
+				algorithm:              DkimAlgorithmRsaSha256,
+				headerCanonicalization: canon,
+				bodyCanonicalization:   canon,
+			}
+			signed, err := s.Sign([]byte(rfc8463Message), time.Now())
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			verifyDkimSignature(t, signed, key.Public())
+		})
+	}
+}
+
+func getRfc8463Key(t *testing.T) ed25519.PrivateKey {
+	seed, err := base64.StdEncoding.DecodeString(rfc8463Seed)
+	if err != nil {
+		t.Fatalf("DecodeString() error = %v", err)
+	}
+	return ed25519.NewKeyFromSeed(seed)
+}
+
+// verifyDkimSignature checks the bh= and b= tags of the DKIM-Signature header at the top of the given message as a
+// receiving server would, returning the tags of the header.
+func verifyDkimSignature(t *testing.T, msg []byte, pub crypto.PublicKey) map[string]string {
+	t.Helper()
+
+	rawHeaders, body, found := strings.Cut(string(msg), "\r\n\r\n")
+	if !found {
+		t.Fatal("message has no body")
+	}
+	headers := splitDkimHeaders(rawHeaders + "\r\n")
+	sigHeader := headers[0]
+	if getDkimHeaderName(sigHeader) != "DKIM-Signature" {
+		t.Fatalf("first header is %q, want DKIM-Signature", sigHeader)
+	}
+
+	_, tagList, _ := strings.Cut(sigHeader, ":")
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(tagList, ";") {
+		name, value, _ := strings.Cut(tag, "=")
+		tags[strings.TrimSpace(name)] = strings.Join(strings.Fields(value), "")
+	}
+	headerCanon, bodyCanon, _ := strings.Cut(tags["c"], "/")
+	s := DkimSigner{headerCanonicalization: headerCanon}
+
+	canonBody := canonicalizeBodySimple(body)
+	if bodyCanon == DkimCanonicalizationRelaxed {
+		canonBody = canonicalizeBodyRelaxed(body)
+	}
+	bodyHash := sha256.Sum256([]byte(canonBody))
+	if got := base64.StdEncoding.EncodeToString(bodyHash[:]); got != tags["bh"] {
+		t.Errorf("bh = %s, want %s", tags["bh"], got)
+	}
+
+	var hashInput strings.Builder
+	used := make(map[int]bool)
+	for _, name := range strings.Split(tags["h"], ":") {
+		for i := len(headers) - 1; i > 0; i-- {
+			if !used[i] && strings.EqualFold(getDkimHeaderName(headers[i]), name) {
+				used[i] = true
+				hashInput.WriteString(s.canonicalizeHeader(headers[i]))
+				break
+			}
+		}
+	}
+	bIndex := strings.LastIndex(sigHeader, "b=")
+	unsigned := sigHeader[:bIndex+len("b=")] + "\r\n"
+	hashInput.WriteString(strings.TrimSuffix(s.canonicalizeHeader(unsigned), "\r\n"))
+	hash := sha256.Sum256([]byte(hashInput.String()))
+
+	signature, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		t.Fatalf("b= is not base64: %v", err)
+	}
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, hash[:], signature) {
+			t.Error("b= does not verify with the Ed25519 public key")
+		}
+	case *rsa.PublicKey:
+		if err = rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], signature); err != nil {
+			t.Errorf("b= does not verify with the RSA public key: %v", err)
+		}
+	default:
+		t.Fatalf("unsupported public key %T", pub)
+	}
+	return tags
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"database/sql"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"time"
+)
+
+const EmailLogTypeDirect = "direct" //sent right away by the service handling the request
+const EmailLogTypeOutbox = "outbox" //sent in the background by the EmailOutboxService
+const EmailLogStatusSent = "sent"
+const EmailLogStatusFailed = "failed"
+const EmailLogSearchLimit = 100
+
+// EmailLogEntry is the record of an attempt at sending an email, kept for support and auditing. Failed attempts are
+// recorded too, so an email retried from the outbox may have several entries.
+type EmailLogEntry struct { //business/domain object
+	Id            int64 `db:"log_id"`
+	Type          string
+	Recipient     string
+	Template      string
+	Locale        string
+	OutboxId      sql.NullInt64 `db:"outbox_id"`
+	Transport     string        //one of the EmailTransport names
+	Status        string
+	Result        sql.NullString //description of the result given by the EmailTransport, or the error
+	MessageId     sql.NullString `db:"message_id"` //Message-ID header, not generated if the email could not be built
+	DateAttempted string         `db:"attempted_on"`
+}
+
+// newEmailLogEntry starts the record of an attempt at sending the email with the given template to the given
+// recipient. It is completed by recordResult once the attempt is over.
+func newEmailLogEntry(logType string, rcptAddr string, locale string, template string) EmailLogEntry {
+	return EmailLogEntry{
+		Type:      logType,
+		Recipient: rcptAddr,
+		Template:  template,
+		Locale:    locale,
+		Transport: GetEmailTransportName(),
+	}
+}
+
+// recordResult records the result of the attempt, which failed if the given error message is not empty.
+func (e *EmailLogEntry) recordResult(messageId string, result string, errMsg string, now time.Time) {
+	e.MessageId = sql.NullString{String: messageId, Valid: messageId != ""}
+	if len(result) > 255 {
+		result = result[:255]
+	}
+	e.Result = sql.NullString{String: result, Valid: result != ""}
+	e.Status = EmailLogStatusSent
+	if errMsg != "" {
+		e.Status = EmailLogStatusFailed
+		if !e.Result.Valid {
+			e.Result = sql.NullString{String: errMsg, Valid: true}
+		}
+	}
+	e.DateAttempted = now.Format(FormatDateTime)
+}
+
+func (e EmailLogEntry) ToDTO() dto.EmailLogResponse {
+	return dto.EmailLogResponse{
+		LogId:         e.Id,
+		Type:          e.Type,
+		Recipient:     e.Recipient,
+		Template:      e.Template,
+		Locale:        e.Locale,
+		OutboxId:      e.OutboxId.Int64,
+		Transport:     e.Transport,
+		Status:        e.Status,
+		Result:        e.Result.String,
+		MessageId:     e.MessageId.String,
+		DateAttempted: e.DateAttempted,
+	}
+}
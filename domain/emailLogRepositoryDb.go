@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type EmailLogRepository interface { //repo (secondary port)
+	Save(EmailLogEntry) *errs.AppError
+	Find(dto.EmailLogSearchRequest) ([]EmailLogEntry, *errs.AppError)
+	FindAllFromRecipient(string) ([]EmailLogEntry, *errs.AppError)
+}
+
+type EmailLogRepositoryDb struct { //DB (adapter)
+	client *sqlx.DB
+}
+
+func NewEmailLogRepositoryDb(dbClient *sqlx.DB) EmailLogRepositoryDb {
+	return EmailLogRepositoryDb{dbClient}
+}
+
+func (d EmailLogRepositoryDb) Save(entry EmailLogEntry) *errs.AppError {
+	insertSql := `INSERT INTO email_log (type, recipient, template, locale, outbox_id, transport, status, result, 
+		message_id, attempted_on) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err := d.client.Exec(insertSql, entry.Type, entry.Recipient, entry.Template, entry.Locale, entry.OutboxId,
+		entry.Transport, entry.Status, entry.Result, entry.MessageId, entry.DateAttempted); err != nil {
+		logger.Error("Error while saving email log entry: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// Find retrieves the latest EmailLogSearchLimit entries matching all the criteria given in the request, latest first.
+// The dates are compared with the whole day in UTC.
+func (d EmailLogRepositoryDb) Find(req dto.EmailLogSearchRequest) ([]EmailLogEntry, *errs.AppError) {
+	entries := make([]EmailLogEntry, 0)
+	findSql := `SELECT * FROM email_log WHERE (? = '' OR recipient = ?) AND (? = '' OR template = ?) 
+		AND (? = '' OR type = ?) AND (? = '' OR status = ?) AND (? = '' OR message_id = ?) 
+		AND (? = '' OR attempted_on >= ?) AND (? = '' OR attempted_on < DATE_ADD(?, INTERVAL 1 DAY)) 
+		ORDER BY log_id DESC LIMIT ?`
+	err := d.client.Select(&entries, findSql, req.Recipient, req.Recipient, req.Template, req.Template,
+		req.Type, req.Type, req.Status, req.Status, req.MessageId, req.MessageId, req.From, req.From, req.To, req.To,
+		EmailLogSearchLimit)
+	if err != nil {
+		logger.Error("Error while searching email log: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return entries, nil
+}
+
+// FindAllFromRecipient retrieves all the entries of emails sent to the given recipient, the latest first.
+func (d EmailLogRepositoryDb) FindAllFromRecipient(rcptAddr string) ([]EmailLogEntry, *errs.AppError) {
+	entries := make([]EmailLogEntry, 0)
+	if err := d.client.Select(&entries, "SELECT * FROM email_log WHERE recipient = ? ORDER BY log_id DESC", rcptAddr); err != nil {
+		logger.Error("Error while retrieving email log entries of recipient: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// buildMimeMessage forms a MIME multipart/alternative message with the given headers, plain-text body and HTML body,
+// using CRLF line endings throughout. The plain-text part comes first so that clients able to display HTML prefer it.
+// The Message-ID generated for the message is also returned.
+func buildMimeMessage(from string, to string, subject string, textBody string, htmlBody string) (string, string, error) {
+	randomId, appErr := GenerateRandomId()
+	if appErr != nil {
+		return "", "", fmt.Errorf("error generating message id")
+	}
+	messageId := "<" + randomId + "@" + getEmailDomain(from) + ">"
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := writeQuotedPrintablePart(mw, "text/plain; charset=UTF-8", textBody); err != nil {
+		return "", "", err
+	}
+	if err := writeQuotedPrintablePart(mw, "text/html; charset=UTF-8", htmlBody); err != nil {
+		return "", "", err
+	}
+	if err := mw.Close(); err != nil {
+		return "", "", err
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + from + "\r\n")
+	msg.WriteString("To: " + to + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("UTF-8", strings.TrimSpace(subject)) + "\r\n")
+	msg.WriteString("Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("Message-ID: " + messageId + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: multipart/alternative; boundary=\"" + mw.Boundary() + "\"\r\n")
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.String(), messageId, nil
+}
+
+// writeQuotedPrintablePart adds a part with the given content type to the given multipart message, with the given
+// content normalised to CRLF line endings and quoted-printable encoded.
+func writeQuotedPrintablePart(mw *multipart.Writer, contentType string, content string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\n", "\r\n")
+	qw := quotedprintable.NewWriter(part)
+	if _, err = qw.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+// getEmailDomain returns the domain part of the given email address, or "localhost" if it has none.
+func getEmailDomain(addr string) string {
+	if i := strings.LastIndex(addr, "@"); i != -1 && i < len(addr)-1 {
+		return strings.Trim(addr[i+1:], "> ")
+	}
+	return "localhost"
+}
@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"time"
+)
+
+const EmailOutboxStatusPending = "pending"
+const EmailOutboxStatusSent = "sent"
+const EmailOutboxStatusDead = "dead" //gave up after EmailOutboxMaxAttempts, until requeued by an admin
+const EmailOutboxMaxAttempts = 8
+const EmailOutboxPollInterval = time.Second * 5
+const EmailOutboxBatchSize = 20
+const EmailOutboxClaimDuration = time.Minute //how long a worker has to send a claimed email before it is retried
+const EmailOutboxInitialBackoff = time.Second * 30
+const EmailOutboxMaxBackoff = time.Hour
+
+// OutboxEmail is an email waiting in the outbox to be rendered from its template and delivered by the background
+// worker, or the record of one that was delivered or dead-lettered.
+type OutboxEmail struct { //business/domain object
+	Id            int64 `db:"outbox_id"`
+	Recipient     string
+	Locale        string
+	Template      string
+	TokenId       sql.NullString `db:"token_id"` //of the one-time token the email is about, if any
+	Data          sql.NullString //JSON of the template data, cleared once sent as it may contain one-time links
+	Status        string
+	Attempts      int
+	NextAttemptOn string         `db:"next_attempt_on"`
+	LastError     sql.NullString `db:"last_error"`
+	DateCreated   string         `db:"created_on"`
+	DateSent      sql.NullString `db:"sent_on"`
+}
+
+// NewOutboxEmail creates a new OutboxEmail to the given recipient using the template with the given name in the given
+// locale and the given template data, to be delivered as soon as possible.
+func NewOutboxEmail(rcptAddr string, locale string, template string, data map[string]any) (*OutboxEmail, *errs.AppError) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		logger.Error("Error while marshalling template data of email: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected server-side error")
+	}
+
+	now := time.Now().UTC().Format(FormatDateTime)
+	return &OutboxEmail{
+		Recipient:     rcptAddr,
+		Locale:        locale,
+		Template:      template,
+		Data:          sql.NullString{String: string(content), Valid: true},
+		Status:        EmailOutboxStatusPending,
+		NextAttemptOn: now,
+		DateCreated:   now,
+	}, nil
+}
+
+// NewRegistrationDecisionEmail creates a new OutboxEmail informing the given recipient whether their registration was
+// approved after review, along with the reason given by the reviewer if any.
+func NewRegistrationDecisionEmail(rcptAddr string, locale string, isApproved bool, reason string) (*OutboxEmail, *errs.AppError) {
+	return NewOutboxEmail(rcptAddr, locale, EmailTemplateRegistrationDecision, map[string]any{
+		"IsApproved": isApproved,
+		"Reason":     reason,
+		"LoginURL":   buildLoginURL(),
+	})
+}
+
+// NewUsernameReminderEmail creates a new OutboxEmail sending the given username to the given recipient.
+func NewUsernameReminderEmail(rcptAddr string, locale string, username string) (*OutboxEmail, *errs.AppError) {
+	return NewOutboxEmail(rcptAddr, locale, EmailTemplateUsernameReminder, map[string]any{
+		"Username": username,
+		"LoginURL": buildLoginURL(),
+	})
+}
+
+// ForToken returns the OutboxEmail linked to the one-time token with the given ID.
+func (e OutboxEmail) ForToken(tokenId string) OutboxEmail {
+	e.TokenId = sql.NullString{String: tokenId, Valid: true}
+	return e
+}
+
+// GetTemplateData returns the template data of the OutboxEmail.
+func (e OutboxEmail) GetTemplateData() (map[string]any, *errs.AppError) {
+	data := make(map[string]any)
+	if err := json.Unmarshal([]byte(e.Data.String), &data); err != nil {
+		logger.Error("Error while unmarshalling template data of email: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected server-side error")
+	}
+	return data, nil
+}
+
+// RecordFailure records the given error from the latest attempt at delivering the OutboxEmail at the given time.
+// The next attempt is scheduled with exponential back-off, unless EmailOutboxMaxAttempts have been made, in which
+// case the OutboxEmail is dead-lettered.
+func (e *OutboxEmail) RecordFailure(errMsg string, now time.Time) {
+	e.LastError = sql.NullString{String: errMsg, Valid: true}
+	if e.Attempts >= EmailOutboxMaxAttempts {
+		e.Status = EmailOutboxStatusDead
+		return
+	}
+	e.NextAttemptOn = now.Add(getOutboxBackoff(e.Attempts)).Format(FormatDateTime)
+}
+
+func (e OutboxEmail) IsDead() bool {
+	return e.Status == EmailOutboxStatusDead
+}
+
+// getOutboxBackoff returns how long to wait after the given number of failed attempts, starting from
+// EmailOutboxInitialBackoff and doubling after each attempt up to EmailOutboxMaxBackoff.
+func getOutboxBackoff(attempts int) time.Duration {
+	backoff := EmailOutboxInitialBackoff
+	for i := 1; i < attempts && backoff < EmailOutboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > EmailOutboxMaxBackoff {
+		return EmailOutboxMaxBackoff
+	}
+	return backoff
+}
+
+func (e OutboxEmail) ToDTO() dto.OutboxEmailResponse {
+	return dto.OutboxEmailResponse{
+		OutboxId:      e.Id,
+		Recipient:     e.Recipient,
+		Locale:        e.Locale,
+		Template:      e.Template,
+		Status:        e.Status,
+		Attempts:      e.Attempts,
+		NextAttemptOn: e.NextAttemptOn,
+		LastError:     e.LastError.String,
+		DateCreated:   e.DateCreated,
+		DateSent:      e.DateSent.String,
+	}
+}
@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type EmailOutboxRepository interface { //repo (secondary port)
+	FindDue(string, int) ([]OutboxEmail, *errs.AppError)
+	Claim(*OutboxEmail, string) (bool, *errs.AppError)
+	MarkSent(int64, string) *errs.AppError
+	UpdateFailure(OutboxEmail) *errs.AppError
+	FindAll(string) ([]OutboxEmail, *errs.AppError)
+	Requeue(int64, string) *errs.AppError
+}
+
+type EmailOutboxRepositoryDb struct { //DB (adapter)
+	client *sqlx.DB
+}
+
+func NewEmailOutboxRepositoryDb(dbClient *sqlx.DB) EmailOutboxRepositoryDb {
+	return EmailOutboxRepositoryDb{dbClient}
+}
+
+// enqueueEmail adds the given OutboxEmail to the outbox. It is shared with other repos so that the email is only
+// delivered if the rest of their transaction is committed.
+func enqueueEmail(e sqlx.Execer, email OutboxEmail) error {
+	insertSql := `INSERT INTO email_outbox (recipient, locale, template, token_id, data, status, attempts, next_attempt_on, 
+		created_on) VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?)`
+	_, err := e.Exec(insertSql, email.Recipient, email.Locale, email.Template, email.TokenId, email.Data, email.Status,
+		email.NextAttemptOn, email.DateCreated)
+	return err
+}
+
+// FindDue retrieves at most the given number of pending emails whose next attempt is due at the given time, oldest
+// first.
+func (d EmailOutboxRepositoryDb) FindDue(now string, limit int) ([]OutboxEmail, *errs.AppError) {
+	emails := make([]OutboxEmail, 0)
+	findSql := `SELECT * FROM email_outbox WHERE status = ? AND next_attempt_on <= ?
+		ORDER BY next_attempt_on, outbox_id LIMIT ?`
+	if err := d.client.Select(&emails, findSql, EmailOutboxStatusPending, now, limit); err != nil {
+		logger.Error("Error while retrieving emails due for delivery: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return emails, nil
+}
+
+// Claim counts a new attempt at delivering the given OutboxEmail and holds off other attempts until the given time,
+// so that it is not delivered twice by concurrent workers. It returns false instead if another worker claimed the
+// email first, in which case it should be skipped. The given OutboxEmail is updated on success.
+func (d EmailOutboxRepositoryDb) Claim(email *OutboxEmail, claimedUntil string) (bool, *errs.AppError) {
+	updateSql := `UPDATE email_outbox SET attempts = attempts + 1, next_attempt_on = ?
+		WHERE outbox_id = ? AND status = ? AND attempts = ? AND next_attempt_on = ?`
+	result, err := d.client.Exec(updateSql, claimedUntil, email.Id, EmailOutboxStatusPending, email.Attempts,
+		email.NextAttemptOn)
+	if err != nil {
+		logger.Error("Error while claiming email for delivery: " + err.Error())
+		return false, errs.NewUnexpectedError("Unexpected database error")
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, nil
+	}
+
+	email.Attempts++
+	email.NextAttemptOn = claimedUntil
+	return true, nil
+}
+
+// MarkSent records that the email with the given outbox ID was delivered at the given time, clearing its template
+// data.
+func (d EmailOutboxRepositoryDb) MarkSent(outboxId int64, timeSent string) *errs.AppError {
+	updateSql := "UPDATE email_outbox SET status = ?, data = NULL, last_error = NULL, sent_on = ? WHERE outbox_id = ?"
+	if _, err := d.client.Exec(updateSql, EmailOutboxStatusSent, timeSent, outboxId); err != nil {
+		logger.Error("Error while marking email as sent: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// UpdateFailure saves the outcome of a failed attempt at delivering the given OutboxEmail, as recorded through
+// OutboxEmail.RecordFailure.
+func (d EmailOutboxRepositoryDb) UpdateFailure(email OutboxEmail) *errs.AppError {
+	updateSql := "UPDATE email_outbox SET status = ?, next_attempt_on = ?, last_error = ? WHERE outbox_id = ?"
+	if _, err := d.client.Exec(updateSql, email.Status, email.NextAttemptOn, email.LastError, email.Id); err != nil {
+		logger.Error("Error while recording failed delivery of email: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// FindAll retrieves the latest emails in the outbox with the given status, or with any status if none is given,
+// latest first.
+func (d EmailOutboxRepositoryDb) FindAll(status string) ([]OutboxEmail, *errs.AppError) {
+	emails := make([]OutboxEmail, 0)
+	findSql := `SELECT * FROM email_outbox WHERE ? = '' OR status = ? ORDER BY outbox_id DESC LIMIT 100`
+	if err := d.client.Select(&emails, findSql, status, status); err != nil {
+		logger.Error("Error while retrieving emails in outbox: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return emails, nil
+}
+
+// Requeue makes the dead-lettered email with the given outbox ID pending again from the given time, with its
+// attempts reset.
+func (d EmailOutboxRepositoryDb) Requeue(outboxId int64, now string) *errs.AppError {
+	var status string
+	if err := d.client.Get(&status, "SELECT status FROM email_outbox WHERE outbox_id = ?", outboxId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("The given email does not exist in the outbox")
+			return errs.NewNotFoundError("Email not found")
+		}
+		logger.Error("Error while retrieving email in outbox: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	if status != EmailOutboxStatusDead {
+		logger.Error("Cannot requeue email as it is not dead-lettered")
+		return errs.NewConflictError("Email is not dead-lettered")
+	}
+
+	updateSql := "UPDATE email_outbox SET status = ?, attempts = 0, next_attempt_on = ? WHERE outbox_id = ? AND status = ?"
+	if _, err := d.client.Exec(updateSql, EmailOutboxStatusPending, now, outboxId, EmailOutboxStatusDead); err != nil {
+		logger.Error("Error while requeueing email: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
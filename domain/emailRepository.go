@@ -1,101 +1,118 @@
 package domain
 
 import (
-	"crypto/tls"
+	"database/sql"
 	"fmt"
 	"github.com/aliciatay-zls/banking-lib/errs"
 	"github.com/aliciatay-zls/banking-lib/logger"
-	"net/smtp"
+	"net/url"
 	"os"
 	"time"
 )
 
 type EmailRepository interface { //repo (secondary port)
-	SendConfirmationEmail(string, string) (string, *errs.AppError)
+	SendOutboxEmail(OutboxEmail) (string, *errs.AppError)
 }
 
 type DefaultEmailRepository struct { //adapter
-	serverUser     string
-	serverPassword string
-	senderEmail    string
+	senderEmail string
+	templates   *EmailTemplates
+	transport   EmailTransport
+	dkimSigner  *DkimSigner
+	logRepo     EmailLogRepository
 }
 
-func NewDefaultEmailRepository() DefaultEmailRepository {
+func NewDefaultEmailRepository(logRepo EmailLogRepository) DefaultEmailRepository {
+	templates, err := LoadEmailTemplates()
+	if err != nil {
+		logger.Fatal("Error while loading email templates: " + err.Error())
+	}
+
 	return DefaultEmailRepository{
-		serverUser:     os.Getenv("MAIL_SERVER_USER"),
-		serverPassword: os.Getenv("MAIL_SERVER_PASSWORD"),
-		senderEmail:    os.Getenv("MAIL_SENDER"),
+		senderEmail: os.Getenv("MAIL_SENDER"),
+		templates:   templates,
+		transport:   NewEmailTransport(),
+		dkimSigner:  NewDkimSigner(),
+		logRepo:     logRepo,
 	}
 }
 
-// SendConfirmationEmail opens a new connection with the remote SMTP server, initiates use of TLS and authenticates
-// itself to the server in production mode, registers the sender and recipient, then sends the email body.
-// It returns the time the email was sent.
-func (d DefaultEmailRepository) SendConfirmationEmail(rcptAddr string, link string) (string, *errs.AppError) {
-	mailServerAddr := os.Getenv("MAIL_SERVER_ADDRESS")
-	mailServerPort := os.Getenv("MAIL_SERVER_PORT")
-	addr := fmt.Sprintf("%s:%s", mailServerAddr, mailServerPort)
+// SendOutboxEmail renders and sends the given OutboxEmail using its template, locale and template data. It returns the
+// time the email was sent.
+func (d DefaultEmailRepository) SendOutboxEmail(email OutboxEmail) (string, *errs.AppError) {
+	entry := newEmailLogEntry(EmailLogTypeOutbox, email.Recipient, email.Locale, email.Template)
+	entry.OutboxId = sql.NullInt64{Int64: email.Id, Valid: true}
 
-	client, err := smtp.Dial(addr)
+	data, err := email.GetTemplateData()
 	if err != nil {
-		logger.Error("Error while connecting to SMTP server: " + err.Error())
-		return "", errs.NewUnexpectedError("Unexpected error sending confirmation email")
-	}
-
-	if os.Getenv("APP_ENV") == "production" {
-		logger.Info("Initiating TLS session with remote SMTP server...")
-		tlsConfig := &tls.Config{ServerName: mailServerAddr}
-		if err = client.StartTLS(tlsConfig); err != nil {
-			logger.Error("Error initiating TLS session: " + err.Error())
-			return "", errs.NewUnexpectedError("Unexpected error sending confirmation email")
-		}
-
-		logger.Info("Authenticating with remote SMTP server...")
-		auth := smtp.PlainAuth("", d.serverUser, d.serverPassword, mailServerAddr)
-		if err = client.Auth(auth); err != nil {
-			logger.Error("Error authenticating with mail server: " + err.Error())
-			return "", errs.NewUnexpectedError("Unexpected error sending confirmation email")
-		}
+		d.log(entry, "", "", err)
+		return "", err
 	}
+	return d.sendAndLog(entry, data)
+}
 
-	if err = client.Mail(d.senderEmail); err != nil {
-		logger.Error("Error while setting the sender: " + err.Error())
-		return "", errs.NewUnexpectedError("Unexpected error sending confirmation email")
-	}
-	if err = client.Rcpt(rcptAddr); err != nil {
-		logger.Error(fmt.Sprintf("Error setting the recipient %s: %s", rcptAddr, err.Error()))
-		return "", errs.NewUnexpectedError("Unexpected error sending confirmation email")
+// sendAndLog renders the email described by the given EmailLogEntry using the given data and sends it, then completes
+// the entry with the result and saves it to the email log. It returns the time the email was sent.
+func (d DefaultEmailRepository) sendAndLog(entry EmailLogEntry, data map[string]any) (string, *errs.AppError) {
+	subject, textBody, htmlBody, err := d.templates.Render(entry.Template, entry.Locale, data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error while rendering email template %s: %s", entry.Template, err.Error()))
+		appErr := errs.NewUnexpectedError("Unexpected error sending email")
+		d.log(entry, "", "rendering: "+err.Error(), appErr)
+		return "", appErr
 	}
 
-	wc, err := client.Data()
+	email, messageId, err := buildMimeMessage(d.senderEmail, entry.Recipient, subject, textBody, htmlBody)
 	if err != nil {
-		logger.Error("Error getting writer: " + err.Error())
-		return "", errs.NewUnexpectedError("Unexpected error sending confirmation email")
+		logger.Error("Error while building email: " + err.Error())
+		appErr := errs.NewUnexpectedError("Unexpected error sending email")
+		d.log(entry, "", "building: "+err.Error(), appErr)
+		return "", appErr
 	}
-	if _, err = wc.Write([]byte(d.buildEmail(rcptAddr, link))); err != nil {
-		logger.Error("Error sending email body: " + err.Error())
-		return "", errs.NewUnexpectedError("Unexpected error sending confirmation email")
+
+	timeSent, result, appErr := d.send(entry.Recipient, email)
+	d.log(entry, messageId, result, appErr)
+	return timeSent, appErr
+}
+
+// log completes the given EmailLogEntry with the given result and saves it. Failing to do so is only logged, as the
+// email was already sent or failed.
+func (d DefaultEmailRepository) log(entry EmailLogEntry, messageId string, result string, sendErr *errs.AppError) {
+	errMsg := ""
+	if sendErr != nil {
+		errMsg = sendErr.Message
 	}
-	if err = wc.Close(); err != nil {
-		logger.Error("Error closing writer: " + err.Error())
-		return "", errs.NewUnexpectedError("Unexpected error sending confirmation email")
+	entry.recordResult(messageId, result, errMsg, time.Now().UTC())
+	_ = d.logRepo.Save(entry)
+}
+
+// buildLoginURL returns the link to the login page of the frontend.
+func buildLoginURL() string {
+	u := url.URL{
+		Scheme: "https",
+		Host:   os.Getenv("FRONTEND_SERVER_DOMAIN"),
+		Path:   "login",
 	}
+	return u.String()
+}
 
-	if err = client.Quit(); err != nil {
-		logger.Error("Error while closing connection to SMTP server: " + err.Error())
-		return "", errs.NewUnexpectedError("Unexpected error sending confirmation email")
+// send signs the given email with DKIM if configured, then passes it to the configured EmailTransport. It returns the
+// time the email was sent and the result given by the EmailTransport.
+func (d DefaultEmailRepository) send(rcptAddr string, email string) (string, string, *errs.AppError) {
+	now := time.Now().UTC()
+	msg := []byte(email)
+	if d.dkimSigner != nil {
+		var err error
+		if msg, err = d.dkimSigner.Sign(msg, now); err != nil {
+			logger.Error("Error while signing email: " + err.Error())
+			return "", "signing: " + err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+		}
 	}
 
-	return time.Now().UTC().Format(FormatDateTime), nil
-}
+	result, err := d.transport.Send(d.senderEmail, rcptAddr, msg)
+	if err != nil {
+		return "", result, err
+	}
 
-// buildEmail forms the email using the recipient's email address and unique confirmation link.
-func (d DefaultEmailRepository) buildEmail(rcptAddr string, link string) string {
-	return "From: " + d.senderEmail + "\r\n" +
-		"To: " + rcptAddr + "\r\n" +
-		"Subject: Email Confirmation [action required]\r\n" +
-		"\r\n" +
-		"Please click on the link below within the next 1 hour to complete your account registration:\n\n" +
-		link + "\n\n" +
-		"If it cannot be clicked, copy and paste it into the address bar of your web browser.\r\n"
+	return now.Format(FormatDateTime), result, nil
 }
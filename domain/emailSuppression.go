@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+const EmailSuppressionReasonBounce = "bounce"
+const EmailSuppressionReasonComplaint = "complaint"
+const MaxFeedbackReportSize = 1 << 20 //1 MB
+
+// EmailSuppression records that an email address must not be sent emails anymore, either because it hard-bounced
+// (e.g. the mailbox does not exist) or because its owner marked an email from us as spam.
+type EmailSuppression struct { //business/domain object
+	Email          string
+	Reason         string
+	Diagnostic     sql.NullString //e.g. the SMTP reply of the receiving server, for support
+	DateSuppressed string         `db:"suppressed_on"`
+}
+
+func newEmailSuppression(email string, reason string, diagnostic string) EmailSuppression {
+	if len(diagnostic) > 255 {
+		diagnostic = diagnostic[:255]
+	}
+	return EmailSuppression{
+		Email:          email,
+		Reason:         reason,
+		Diagnostic:     sql.NullString{String: diagnostic, Valid: diagnostic != ""},
+		DateSuppressed: time.Now().UTC().Format(FormatDateTime),
+	}
+}
+
+// ParseFeedbackReport extracts the addresses to suppress from the given report sent back by a receiving server or
+// mailbox provider, which is either a delivery status notification (RFC 3464) or a complaint in the Abuse Reporting
+// Format (RFC 5965). Only permanent failures are returned for the former, as temporary ones (e.g. a full mailbox) may
+// succeed on a later attempt.
+func ParseFeedbackReport(report []byte) ([]EmailSuppression, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(report))
+	if err != nil {
+		return nil, err
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	if mediaType != "multipart/report" {
+		return nil, errors.New("not a multipart/report message")
+	}
+
+	var suppressions []EmailSuppression
+	var complaintRcpts []string
+	isComplaint := false
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case "message/delivery-status", "message/global-delivery-status":
+			fieldGroups, err := readReportFieldGroups(part)
+			if err != nil {
+				return nil, err
+			}
+			//the first group describes the message, the others each describe a recipient
+			for _, fields := range fieldGroups[1:] {
+				if s, ok := getBounceSuppression(fields); ok {
+					suppressions = append(suppressions, s)
+				}
+			}
+		case "message/feedback-report":
+			fieldGroups, err := readReportFieldGroups(part)
+			if err != nil {
+				return nil, err
+			}
+			isComplaint = true
+			complaintRcpts = append(complaintRcpts, fieldGroups[0].Values("Original-Rcpt-To")...)
+		case "message/rfc822", "text/rfc822-headers":
+			//the original message, whose recipient is needed if the complaint does not say who complained
+			if isComplaint && len(complaintRcpts) == 0 {
+				original, err := mail.ReadMessage(part)
+				if err != nil {
+					return nil, err
+				}
+				if addrs, err := original.Header.AddressList("To"); err == nil {
+					for _, addr := range addrs {
+						complaintRcpts = append(complaintRcpts, addr.Address)
+					}
+				}
+			}
+		}
+	}
+
+	for _, rcpt := range complaintRcpts {
+		if addr, err := mail.ParseAddress(rcpt); err == nil {
+			suppressions = append(suppressions, newEmailSuppression(addr.Address, EmailSuppressionReasonComplaint, ""))
+		}
+	}
+	return suppressions, nil
+}
+
+// readReportFieldGroups reads the blank line-separated groups of header-like fields that make up the machine-readable
+// part of a report.
+func readReportFieldGroups(r io.Reader) ([]textproto.MIMEHeader, error) {
+	reader := textproto.NewReader(bufio.NewReader(r))
+	var groups []textproto.MIMEHeader
+	for {
+		fields, err := reader.ReadMIMEHeader()
+		if len(fields) > 0 {
+			groups = append(groups, fields)
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(groups) == 0 {
+		return nil, errors.New("empty report")
+	}
+	return groups, nil
+}
+
+// getBounceSuppression returns the suppression for the recipient described by the given DSN fields, if delivery to
+// it failed permanently (a 5.X.X status).
+func getBounceSuppression(fields textproto.MIMEHeader) (EmailSuppression, bool) {
+	if !strings.EqualFold(fields.Get("Action"), "failed") || !strings.HasPrefix(fields.Get("Status"), "5.") {
+		return EmailSuppression{}, false
+	}
+
+	//e.g. "rfc822; someone@example.com"
+	_, rcpt, found := strings.Cut(fields.Get("Final-Recipient"), ";")
+	if !found {
+		return EmailSuppression{}, false
+	}
+	addr, err := mail.ParseAddress(strings.TrimSpace(rcpt))
+	if err != nil {
+		return EmailSuppression{}, false
+	}
+
+	diagnostic := fields.Get("Diagnostic-Code")
+	if diagnostic == "" {
+		diagnostic = fields.Get("Status")
+	}
+	return newEmailSuppression(addr.Address, EmailSuppressionReasonBounce, diagnostic), true
+}
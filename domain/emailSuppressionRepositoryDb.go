@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type EmailSuppressionRepository interface { //repo (secondary port)
+	Save([]EmailSuppression) *errs.AppError
+	IsSuppressed(string) (bool, *errs.AppError)
+}
+
+type EmailSuppressionRepositoryDb struct { //DB (adapter)
+	client *sqlx.DB
+}
+
+func NewEmailSuppressionRepositoryDb(dbClient *sqlx.DB) EmailSuppressionRepositoryDb {
+	return EmailSuppressionRepositoryDb{dbClient}
+}
+
+// Save adds the given addresses to the suppression list in one db transaction. Addresses already on it are updated
+// with the latest reason, e.g. a complaint from an address that later stopped existing.
+func (d EmailSuppressionRepositoryDb) Save(suppressions []EmailSuppression) *errs.AppError {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for saving email suppressions: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	upsertSql := `INSERT INTO email_suppressions (email, reason, diagnostic, suppressed_on) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE reason = VALUES(reason), diagnostic = VALUES(diagnostic),
+		suppressed_on = VALUES(suppressed_on)`
+	for _, s := range suppressions {
+		if _, err = tx.Exec(upsertSql, s.Email, s.Reason, s.Diagnostic, s.DateSuppressed); err != nil {
+			logger.Error("Error while saving email suppression: " + err.Error())
+			rollbackTx(tx, "saving of email suppressions")
+			return errs.NewUnexpectedError("Unexpected database error")
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for saving email suppressions: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// IsSuppressed checks whether the given address is on the suppression list.
+func (d EmailSuppressionRepositoryDb) IsSuppressed(email string) (bool, *errs.AppError) {
+	var count int
+	if err := d.client.Get(&count, "SELECT COUNT(*) FROM email_suppressions WHERE email = ?", email); err != nil {
+		logger.Error("Error while checking email suppression list: " + err.Error())
+		return false, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return count > 0, nil
+}
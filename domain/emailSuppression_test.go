@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+// newTestFeedbackReport returns a multipart/report message made up of the given parts, each of which starts with its
+// own headers.
+func newTestFeedbackReport(reportType string, parts ...string) []byte {
+	var b strings.Builder
+	b.WriteString("From: mailer-daemon@example.net\n")
+	b.WriteString("To: no-reply@example.com\n")
+	b.WriteString("MIME-Version: 1.0\n")
+	b.WriteString("Content-Type: multipart/report; report-type=" + reportType + "; boundary=\"b\"\n\n")
+	for _, part := range parts {
+		b.WriteString("--b\n" + part + "\n")
+	}
+	b.WriteString("--b--\n")
+	return []byte(b.String())
+}
+
+func TestParseFeedbackReport(t *testing.T) {
+	const humanPart = "Content-Type: text/plain\n\nYour message could not be delivered.\n"
+	const originalPart = "Content-Type: message/rfc822\n\nFrom: no-reply@example.com\nTo: Jane <jane@example.org>\n" +
+		"Subject: Your username\n\nHello\n"
+	const dsnFields = "Content-Type: message/delivery-status\n\nReporting-MTA: dns; mx.example.org\n\n"
+
+	type suppression struct {
+		email      string
+		reason     string
+		diagnostic string
+	}
+	tests := []struct {
+		name    string
+		report  []byte
+		want    []suppression
+		wantErr bool
+	}{
+		{
+			name: "permanent failure",
+			report: newTestFeedbackReport("delivery-status", humanPart,
+				dsnFields+"Final-Recipient: rfc822; jane@example.org\nAction: failed\nStatus: 5.1.1\n"+
+					"Diagnostic-Code: smtp; 550 5.1.1 User unknown\n"),
+			want: []suppression{{"jane@example.org", EmailSuppressionReasonBounce, "smtp; 550 5.1.1 User unknown"}},
+		},
+		{
+			name: "permanent failure without diagnostic code",
+			report: newTestFeedbackReport("delivery-status", humanPart,
+				dsnFields+"Final-Recipient: rfc822; jane@example.org\nAction: failed\nStatus: 5.2.1\n"),
+			want: []suppression{{"jane@example.org", EmailSuppressionReasonBounce, "5.2.1"}},
+		},
+		{
+			name: "temporary failure",
+			report: newTestFeedbackReport("delivery-status", humanPart,
+				dsnFields+"Final-Recipient: rfc822; jane@example.org\nAction: failed\nStatus: 4.2.2\n"),
+		},
+		{
+			name: "delayed delivery",
+			report: newTestFeedbackReport("delivery-status", humanPart,
+				dsnFields+"Final-Recipient: rfc822; jane@example.org\nAction: delayed\nStatus: 5.0.0\n"),
+		},
+		{
+			name: "only failed recipients among several",
+			report: newTestFeedbackReport("delivery-status", humanPart,
+				dsnFields+"Final-Recipient: rfc822; john@example.org\nAction: delivered\nStatus: 2.0.0\n\n"+
+					"Final-Recipient: rfc822; jane@example.org\nAction: FAILED\nStatus: 5.1.1\n", originalPart),
+			want: []suppression{{"jane@example.org", EmailSuppressionReasonBounce, "5.1.1"}},
+		},
+		{
+			name: "global delivery status",
+			report: newTestFeedbackReport("global-delivery-status", humanPart,
+				"Content-Type: message/global-delivery-status\n\nReporting-MTA: dns; mx.example.org\n\n"+
+					"Final-Recipient: rfc822; jane@example.org\nAction: failed\nStatus: 5.1.1\n"),
+			want: []suppression{{"jane@example.org", EmailSuppressionReasonBounce, "5.1.1"}},
+		},
+		{
+			name: "final recipient without address type",
+			report: newTestFeedbackReport("delivery-status", humanPart,
+				dsnFields+"Final-Recipient: jane@example.org\nAction: failed\nStatus: 5.1.1\n"),
+		},
+		{
+			name: "final recipient not an address",
+			report: newTestFeedbackReport("delivery-status", humanPart,
+				dsnFields+"Final-Recipient: rfc822; jane\nAction: failed\nStatus: 5.1.1\n"),
+		},
+		{
+			name: "complaint with original recipient",
+			report: newTestFeedbackReport("feedback-report", humanPart,
+				"Content-Type: message/feedback-report\n\nFeedback-Type: abuse\nUser-Agent: ExampleFBL/1.0\n"+
+					"Version: 1\nOriginal-Rcpt-To: <jane@example.org>\n", originalPart),
+			want: []suppression{{"jane@example.org", EmailSuppressionReasonComplaint, ""}},
+		},
+		{
+			name: "complaint without original recipient",
+			report: newTestFeedbackReport("feedback-report", humanPart,
+				"Content-Type: message/feedback-report\n\nFeedback-Type: abuse\nUser-Agent: ExampleFBL/1.0\n"+
+					"Version: 1\n", originalPart),
+			want: []suppression{{"jane@example.org", EmailSuppressionReasonComplaint, ""}},
+		},
+		{
+			name:   "original message without complaint",
+			report: newTestFeedbackReport("delivery-status", humanPart, originalPart),
+		},
+		{
+			name:    "not a multipart report",
+			report:  []byte("From: someone@example.org\nContent-Type: text/plain\n\nHello\n"),
+			wantErr: true,
+		},
+		{
+			name:    "missing content type",
+			report:  []byte("From: someone@example.org\n\nHello\n"),
+			wantErr: true,
+		},
+		{
+			name:    "not an email",
+			report:  []byte("hello"),
+			wantErr: true,
+		},
+		{
+			name:    "empty delivery status",
+			report:  newTestFeedbackReport("delivery-status", humanPart, "Content-Type: message/delivery-status\n\n"),
+			wantErr: true,
+		},
+		{
+			name: "malformed delivery status field",
+			report: newTestFeedbackReport("delivery-status", humanPart,
+				dsnFields+"Final-Recipient rfc822; jane@example.org\nAction: failed\nStatus: 5.1.1\n"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFeedbackReport(tt.report)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFeedbackReport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseFeedbackReport() = %+v, want %+v", got, tt.want)
+			}
+			for i, s := range got {
+				if s.Email != tt.want[i].email || s.Reason != tt.want[i].reason ||
+					s.Diagnostic.String != tt.want[i].diagnostic {
+					t.Errorf("ParseFeedbackReport()[%d] = %+v, want %+v", i, s, tt.want[i])
+				}
+			}
+		})
+	}
+}
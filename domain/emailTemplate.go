@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	texttemplate "text/template"
+)
+
+const (
+	EmailTemplateConfirmation            = "confirmation"
+	EmailTemplateRegistrationDecision    = "registration_decision"
+	EmailTemplateEmailChangeConfirmation = "email_change_confirmation"
+	EmailTemplateEmailChangeNotice       = "email_change_notice"
+	EmailTemplateDataExport              = "data_export"
+	EmailTemplateUsernameReminder        = "username_reminder"
+	EmailTemplateInvitation              = "invitation"
+)
+
+var emailTemplateNames = []string{
+	EmailTemplateConfirmation,
+	EmailTemplateRegistrationDecision,
+	EmailTemplateEmailChangeConfirmation,
+	EmailTemplateEmailChangeNotice,
+	EmailTemplateDataExport,
+	EmailTemplateUsernameReminder,
+	EmailTemplateInvitation,
+}
+
+//go:embed templates/email
+var defaultEmailTemplates embed.FS
+
+// EmailTemplates holds the plain-text and HTML version of every email in every supported locale, keyed by
+// "<locale>/<name>". The plain-text version of each email must also define a "subject" template.
+type EmailTemplates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// LoadEmailTemplates parses the templates of every email from the directory set in EMAIL_TEMPLATES_PATH if any,
+// otherwise from the templates built into the app. Each email named <name> in a locale is made up of the files
+// <locale>/<name>.txt and <locale>/<name>.html. Every email must exist in DefaultLocale, while other locales may
+// leave out emails that have not been translated yet.
+func LoadEmailTemplates() (*EmailTemplates, error) {
+	var fsys fs.FS
+	if dir := os.Getenv("EMAIL_TEMPLATES_PATH"); dir != "" {
+		fsys = os.DirFS(dir)
+	} else {
+		var err error
+		if fsys, err = fs.Sub(defaultEmailTemplates, "templates/email"); err != nil {
+			return nil, err
+		}
+	}
+
+	templates := EmailTemplates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+	for _, locale := range SupportedLocales {
+		for _, name := range emailTemplateNames {
+			key := path.Join(locale, name)
+			if _, err := fs.Stat(fsys, key+".txt"); locale != DefaultLocale && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			textTmpl, err := texttemplate.ParseFS(fsys, key+".txt")
+			if err != nil {
+				return nil, err
+			}
+			if textTmpl.Lookup("subject") == nil {
+				return nil, fmt.Errorf("template %s.txt does not define a subject", key)
+			}
+			htmlTmpl, err := htmltemplate.ParseFS(fsys, key+".html")
+			if err != nil {
+				return nil, err
+			}
+
+			templates.text[key] = textTmpl
+			templates.html[key] = htmlTmpl
+		}
+	}
+
+	return &templates, nil
+}
+
+// Render executes the templates of the email with the given name in the given locale using the given data, returning
+// the subject, plain-text body and HTML body of the email. If the email has not been translated into the locale, it
+// is rendered in DefaultLocale instead.
+func (t *EmailTemplates) Render(name string, locale string, data any) (string, string, string, error) {
+	key := path.Join(locale, name)
+	textTmpl, ok := t.text[key]
+	if !ok {
+		key = path.Join(DefaultLocale, name)
+		if textTmpl, ok = t.text[key]; !ok {
+			return "", "", "", fmt.Errorf("no template named %s", name)
+		}
+	}
+	htmlTmpl := t.html[key]
+
+	var subject, text, html bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", "", err
+	}
+	if err := textTmpl.Execute(&text, data); err != nil {
+		return "", "", "", err
+	}
+	if err := htmlTmpl.Execute(&html, data); err != nil {
+		return "", "", "", err
+	}
+
+	return subject.String(), text.String(), html.String(), nil
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"os"
+)
+
+const EmailTransportSmtp = "smtp"
+const EmailTransportFile = "file"
+const EmailTransportHttp = "http"
+
+// EmailTransport delivers fully-formed MIME messages, independently of how they were built. Along with any error, Send
+// returns a short description of the result for the email log, e.g. the response of the provider or the reason for
+// the failure.
+type EmailTransport interface {
+	Send(string, string, []byte) (string, *errs.AppError)
+}
+
+// GetEmailTransportName returns the name of the EmailTransport chosen by the MAIL_TRANSPORT environment variable,
+// which is SMTP by default.
+func GetEmailTransportName() string {
+	if transport := os.Getenv("MAIL_TRANSPORT"); transport != "" {
+		return transport
+	}
+	return EmailTransportSmtp
+}
+
+// NewEmailTransport creates the EmailTransport chosen by the MAIL_TRANSPORT environment variable. Each transport is
+// configured by its own environment variables.
+func NewEmailTransport() EmailTransport {
+	switch transport := GetEmailTransportName(); transport {
+	case EmailTransportSmtp:
+		return NewSmtpEmailTransport()
+	case EmailTransportFile:
+		return NewFileEmailTransport()
+	case EmailTransportHttp:
+		return NewHttpEmailTransport()
+	default:
+		logger.Fatal("Unknown email transport: " + transport)
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const DefaultMailFilePath = "mail"
+
+// FileEmailTransport writes messages to a local maildir instead of sending them, for development and testing without
+// an SMTP server. The maildir can be read by most mail clients, and each message is also a plain .eml file.
+type FileEmailTransport struct { //adapter
+	rootDir string
+}
+
+// NewFileEmailTransport creates a FileEmailTransport writing to the maildir specified by the MAIL_FILE_PATH
+// environment variable (DefaultMailFilePath if not set), creating it if needed.
+func NewFileEmailTransport() FileEmailTransport {
+	rootDir := os.Getenv("MAIL_FILE_PATH")
+	if rootDir == "" {
+		rootDir = DefaultMailFilePath
+	}
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(rootDir, sub), 0700); err != nil {
+			logger.Fatal("Error while creating maildir: " + err.Error())
+		}
+	}
+
+	return FileEmailTransport{rootDir}
+}
+
+// Send delivers the given message into the maildir: it is written to tmp/ first, then moved to new/ so that readers
+// never see a partially-written message. The envelope sender and recipient are recorded as Return-Path and
+// Delivered-To headers, as a local delivery agent would.
+func (t FileEmailTransport) Send(from string, rcptAddr string, msg []byte) (string, *errs.AppError) {
+	id, appErr := GenerateRandomId()
+	if appErr != nil {
+		return "error generating file name", errs.NewUnexpectedError("Unexpected error sending email")
+	}
+	name := fmt.Sprintf("%d.%s.eml", time.Now().UTC().UnixNano(), id)
+
+	content := append([]byte("Return-Path: <"+from+">\r\nDelivered-To: "+rcptAddr+"\r\n"), msg...)
+	tmpPath := filepath.Join(t.rootDir, "tmp", name)
+	if err := os.WriteFile(tmpPath, content, 0600); err != nil {
+		logger.Error("Error while writing email to maildir: " + err.Error())
+		return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+	}
+	newPath := filepath.Join(t.rootDir, "new", name)
+	if err := os.Rename(tmpPath, newPath); err != nil {
+		logger.Error("Error while delivering email to maildir: " + err.Error())
+		return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+	}
+
+	return "written to " + newPath, nil
+}
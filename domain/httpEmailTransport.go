@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const HttpEmailTransportTimeout = time.Second * 10
+
+// HttpEmailTransport sends messages through the JSON API of an email provider, or a local stub of one. Each message is
+// POSTed to the configured URL as {"from": ..., "to": [...], "raw_message": ...}, with the API key, if any, as a
+// bearer token. Any 2xx response means the provider accepted the message.
+type HttpEmailTransport struct { //adapter
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+type httpEmailRequest struct {
+	From       string   `json:"from"`
+	To         []string `json:"to"`
+	RawMessage string   `json:"raw_message"`
+}
+
+// NewHttpEmailTransport creates an HttpEmailTransport using the MAIL_HTTP_URL and MAIL_HTTP_API_KEY environment
+// variables.
+func NewHttpEmailTransport() HttpEmailTransport {
+	url := os.Getenv("MAIL_HTTP_URL")
+	if url == "" {
+		logger.Fatal("Environment variable MAIL_HTTP_URL was not defined")
+	}
+
+	return HttpEmailTransport{
+		url:    url,
+		apiKey: os.Getenv("MAIL_HTTP_API_KEY"),
+		client: &http.Client{Timeout: HttpEmailTransportTimeout},
+	}
+}
+
+// Send posts the given message to the provider. The result is the status and the start of the body of the response,
+// which usually has the ID given to the message by the provider.
+func (t HttpEmailTransport) Send(from string, rcptAddr string, msg []byte) (string, *errs.AppError) {
+	body, err := json.Marshal(httpEmailRequest{From: from, To: []string{rcptAddr}, RawMessage: string(msg)})
+	if err != nil {
+		logger.Error("Error while marshalling email for provider: " + err.Error())
+		return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		logger.Error("Error while creating request to email provider: " + err.Error())
+		return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		logger.Error("Error while sending email to provider: " + err.Error())
+		return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	result := fmt.Sprintf("%d %s", resp.StatusCode, respBody)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		logger.Error(fmt.Sprintf("Email provider rejected email (%d): %s", resp.StatusCode, respBody))
+		return result, errs.NewUnexpectedError("Unexpected error sending email")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"database/sql"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+const InvitationTokenDuration = time.Hour * 72
+
+// Invitation is an invitation by an admin for someone to become a user with one of the RolesWithoutCustomer, e.g. an
+// admin as well. The invitee chooses their own username and password and enrols a TOTP authenticator when accepting it.
+type Invitation struct { //business/domain object
+	InvitationId     string         `db:"invitation_id"`
+	Email            string         `db:"email"`
+	Role             string         `db:"role"`
+	InvitedBy        string         `db:"invited_by"` //username of the admin who sent the invitation
+	MfaSecret        sql.NullString `db:"mfa_secret"` //TOTP secret generated for the invitee, until accepted
+	DateCreated      string         `db:"created_on"`
+	DateExpires      string         `db:"expires_on"`
+	DateAccepted     sql.NullString `db:"accepted_on"`
+	AcceptedUsername sql.NullString `db:"accepted_username"`
+}
+
+// NewInvitation creates a new Invitation to the given email for the given role by the given admin, identified by a
+// random unique ID.
+func NewInvitation(email string, role string, invitedBy string) (*Invitation, *errs.AppError) {
+	if !IsRoleWithoutCustomer(role) {
+		logger.Error("Role of invitation requires a customer ID")
+		return nil, errs.NewValidationError("Role cannot be given by invitation")
+	}
+
+	invitationId, err := GenerateRandomId()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	return &Invitation{
+		InvitationId: invitationId,
+		Email:        email,
+		Role:         role,
+		InvitedBy:    invitedBy,
+		DateCreated:  now.Format(FormatDateTime),
+		DateExpires:  now.Add(InvitationTokenDuration).Format(FormatDateTime),
+	}, nil
+}
+
+// GetInvitationTokenClaims creates the claims for a new one-time token for the Invitation, expiring along with it
+// and with a random unique ID so that the token can be tracked and used only once.
+func (i Invitation) GetInvitationTokenClaims() (*InvitationTokenClaims, *errs.AppError) {
+	tokenId, err := GenerateRandomId()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, timeErr := time.Parse(FormatDateTime, i.DateExpires)
+	if timeErr != nil {
+		logger.Error("Error while parsing expiry of invitation: " + timeErr.Error())
+		return nil, errs.NewUnexpectedError("Unexpected server-side error")
+	}
+
+	return &InvitationTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenId,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		TokenType:    TokenTypeInvitation,
+		InvitationId: i.InvitationId,
+		Email:        i.Email,
+	}, nil
+}
+
+// CheckPending ensures that the Invitation has not been accepted yet.
+func (i Invitation) CheckPending() *errs.AppError {
+	if i.DateAccepted.Valid {
+		logger.Error("Invitation was already accepted")
+		return errs.NewConflictError("Invitation already accepted")
+	}
+	return nil
+}
+
+// CheckMfaCode ensures that a TOTP authenticator has been enrolled for the Invitation and that the given code from
+// it is correct. It returns the time step of the code.
+func (i Invitation) CheckMfaCode(code string) (int64, *errs.AppError) {
+	if !i.MfaSecret.Valid {
+		logger.Error("No TOTP secret generated for invitation yet")
+		return 0, errs.NewValidationError("MFA not enrolled yet")
+	}
+
+	step, ok := VerifyTotpCode(i.MfaSecret.String, code, time.Now().UTC())
+	if !ok {
+		logger.Error("Incorrect TOTP code given for invitation")
+		return 0, errs.NewValidationError("Incorrect MFA code")
+	}
+	return step, nil
+}
+
+func (i Invitation) ToDTO() *dto.InvitationResponse {
+	return &dto.InvitationResponse{
+		InvitationId: i.InvitationId,
+		Email:        i.Email,
+		Role:         i.Role,
+		DateExpires:  i.DateExpires,
+	}
+}
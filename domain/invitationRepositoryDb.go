@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type InvitationRepository interface { //repo (secondary port)
+	Save(Invitation, *InvitationTokenClaims, OutboxEmail) *errs.AppError
+	Find(string) (*Invitation, *errs.AppError)
+	UpdateMfaSecret(string, string) *errs.AppError
+	Accept(string, string, string, string, int64, string) *errs.AppError
+}
+
+type InvitationRepositoryDb struct { //DB (adapter)
+	client *sqlx.DB
+}
+
+func NewInvitationRepositoryDb(dbClient *sqlx.DB) InvitationRepositoryDb {
+	return InvitationRepositoryDb{dbClient}
+}
+
+// Save records the given new Invitation along with the one-time token in the given claims, which invalidates older
+// links sent to the same email, and adds the given OutboxEmail delivering the link to the outbox, in a single db
+// transaction. The email is therefore only sent if the link can be used.
+func (d InvitationRepositoryDb) Save(inv Invitation, claims *InvitationTokenClaims, email OutboxEmail) *errs.AppError {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for saving invitation: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	insertSql := `INSERT INTO invitations (invitation_id, email, role, invited_by, created_on, expires_on) 
+		VALUES (:invitation_id, :email, :role, :invited_by, :created_on, :expires_on)`
+	if _, err = tx.NamedExec(insertSql, inv); err != nil {
+		logger.Error("Error while saving invitation: " + err.Error())
+		rollbackTx(tx, "saving of invitation")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if err = saveOneTimeToken(tx, claims.ID, claims.Email, OneTimeTokenPurposeInvitation, claims.ExpiresAt.Time); err != nil {
+		logger.Error("Error while saving one-time token of invitation: " + err.Error())
+		rollbackTx(tx, "saving of invitation")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if err = enqueueEmail(tx, email.ForToken(claims.ID)); err != nil {
+		logger.Error("Error while enqueueing invitation email: " + err.Error())
+		rollbackTx(tx, "saving of invitation")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for saving invitation: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// Find retrieves the Invitation with the given invitation ID.
+func (d InvitationRepositoryDb) Find(invitationId string) (*Invitation, *errs.AppError) {
+	var inv Invitation
+	if err := d.client.Get(&inv, "SELECT * FROM invitations WHERE invitation_id = ?", invitationId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("The given invitation does not exist")
+			return nil, errs.NewNotFoundError("Invitation not found")
+		}
+		logger.Error("Error while retrieving invitation: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return &inv, nil
+}
+
+// UpdateMfaSecret replaces the TOTP secret generated for the pending Invitation with the given invitation ID.
+func (d InvitationRepositoryDb) UpdateMfaSecret(invitationId string, secret string) *errs.AppError {
+	updateSql := "UPDATE invitations SET mfa_secret = ? WHERE invitation_id = ? AND accepted_on IS NULL"
+	if _, err := d.client.Exec(updateSql, secret, invitationId); err != nil {
+		logger.Error("Error while updating TOTP secret of invitation: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// Accept completes the Invitation with the given invitation ID using the given one-time token in a single db
+// transaction: the invitation is locked and checked to still be pending, the username is checked to be available as
+// during sign-up, the token is used up, then the new user is created with the role of the invitation and the given
+// username and hashed password, without a customer ID, along with their enrolled TOTP secret and the time step of the
+// code used to enrol it.
+func (d InvitationRepositoryDb) Accept(invitationId string, tokenId string, username string, hashedPw string, mfaStep int64, acceptTime string) *errs.AppError {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for accepting invitation: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	var inv Invitation
+	if err = tx.Get(&inv, "SELECT * FROM invitations WHERE invitation_id = ? FOR UPDATE", invitationId); err != nil {
+		rollbackTx(tx, "locking of invitation")
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("The given invitation does not exist")
+			return errs.NewNotFoundError("Invitation not found")
+		}
+		logger.Error("Error while locking invitation: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	if appErr := inv.CheckPending(); appErr != nil {
+		rollbackTx(tx, "locking of accepted invitation")
+		return appErr
+	}
+
+	if appErr := isUsernameTaken(tx, username); appErr != nil {
+		rollbackTx(tx, "checking of username")
+		return appErr
+	}
+
+	if appErr := markOneTimeTokenUsed(tx, tokenId); appErr != nil {
+		rollbackTx(tx, "using up of invitation token")
+		return appErr
+	}
+
+	if _, err = tx.Exec("INSERT INTO users VALUES (?, ?, ?, ?, ?)", username, hashedPw, inv.Role, nil, acceptTime); err != nil {
+		logger.Error("Error while creating invited user: " + err.Error())
+		rollbackTx(tx, "accepting of invitation")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	insertMfaSql := "INSERT INTO user_mfa (username, totp_secret, last_used_step, enrolled_on) VALUES (?, ?, ?, ?)"
+	if _, err = tx.Exec(insertMfaSql, username, inv.MfaSecret, mfaStep, acceptTime); err != nil {
+		logger.Error("Error while enrolling TOTP of invited user: " + err.Error())
+		rollbackTx(tx, "accepting of invitation")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	updateSql := "UPDATE invitations SET accepted_on = ?, accepted_username = ?, mfa_secret = NULL WHERE invitation_id = ?"
+	if _, err = tx.Exec(updateSql, acceptTime, username, invitationId); err != nil {
+		logger.Error("Error while updating accepted invitation: " + err.Error())
+		rollbackTx(tx, "accepting of invitation")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for accepting invitation: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net"
+	"sync"
+	"time"
+)
+
+const IpAccessActionAllow = "allow"
+const IpAccessActionDeny = "deny"
+const IpAccessRouteLogin = "Login" //route name of the login route, the rest are admin routes or checked by Verify
+const IpAccessRouteAddRule = "AddIpRule"
+const IpAccessRouteRemoveRule = "RemoveIpRule"
+const IpAccessReloadInterval = 30 * time.Second
+
+// IpAccessRule allows or denies a role access from the network Cidr, to the route RouteName or to all routes if it
+// is empty. A role is denied access from the networks of its deny rules and, if it has allow rules, from any network
+// not in them.
+type IpAccessRule struct { //business/domain object
+	Id          int64  `db:"rule_id"`
+	Role        string //one of RoleAdmin or RoleUser
+	RouteName   string `db:"route_name"`
+	Cidr        string
+	Action      string
+	CreatedBy   string `db:"created_by"`
+	DateCreated string `db:"created_on"`
+}
+
+func (r IpAccessRule) appliesTo(role string, routeName string) bool {
+	return r.Role == role && (r.RouteName == "" || r.RouteName == routeName)
+}
+
+func (r IpAccessRule) ToDTO() dto.IpAccessRuleResponse {
+	return dto.IpAccessRuleResponse{
+		RuleId:      r.Id,
+		Role:        r.Role,
+		RouteName:   r.RouteName,
+		Cidr:        r.Cidr,
+		Action:      r.Action,
+		CreatedBy:   r.CreatedBy,
+		DateCreated: r.DateCreated,
+	}
+}
+
+// IsIpAllowedByRules checks whether the given rules let the given role access the given route from the given IP
+// address. Access is denied if rules apply but the IP address is missing or cannot be parsed.
+func IsIpAllowedByRules(rules []IpAccessRule, role string, routeName string, ip string) bool {
+	parsedIp := net.ParseIP(ip)
+	hasAllowRules, isInAllowRules := false, false
+	for _, rule := range rules {
+		if !rule.appliesTo(role, routeName) {
+			continue
+		}
+		_, network, err := net.ParseCIDR(rule.Cidr) //stored normalised by ParseCidr
+		isInNetwork := err == nil && parsedIp != nil && network.Contains(parsedIp)
+		if rule.Action == IpAccessActionDeny && (isInNetwork || parsedIp == nil) {
+			return false
+		}
+		if rule.Action == IpAccessActionAllow {
+			hasAllowRules = true
+			isInAllowRules = isInAllowRules || isInNetwork
+		}
+	}
+	return !hasAllowRules || isInAllowRules
+}
+
+// IpAccessList holds the IpAccessRule list in memory so that it can be checked on every request. It is reloaded from
+// the db periodically, so that changes made through any instance of the server take effect everywhere.
+type IpAccessList struct { //business/domain object
+	repo  IpAccessRuleRepository
+	mu    sync.RWMutex
+	rules []IpAccessRule
+}
+
+func NewIpAccessList(repo IpAccessRuleRepository) *IpAccessList {
+	l := &IpAccessList{repo: repo}
+	if appErr := l.Reload(); appErr != nil {
+		logger.Fatal("Error while loading IP access rules: " + appErr.Message)
+	}
+	return l
+}
+
+// Reload replaces the rules held with the ones in the db. The rules held are kept if they cannot be retrieved.
+func (l *IpAccessList) Reload() *errs.AppError {
+	rules, appErr := l.repo.FindAll()
+	if appErr != nil {
+		return appErr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rules = rules
+	return nil
+}
+
+// ReloadPeriodically reloads the rules every IpAccessReloadInterval, indefinitely.
+func (l *IpAccessList) ReloadPeriodically() {
+	for {
+		time.Sleep(IpAccessReloadInterval)
+		_ = l.Reload()
+	}
+}
+
+// GetRules returns a copy of the rules held.
+func (l *IpAccessList) GetRules() []IpAccessRule {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append(make([]IpAccessRule, 0, len(l.rules)), l.rules...)
+}
+
+// Check ensures that the given role can access the given route from the given IP address.
+func (l *IpAccessList) Check(role string, routeName string, ip string) *errs.AppError {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if !IsIpAllowedByRules(l.rules, role, routeName, ip) {
+		logger.Error(fmt.Sprintf("Access by %s to route %s from IP address %s is not allowed", role, routeName, ip))
+		return errs.NewAuthorizationError("Access from this network is not allowed")
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type IpAccessRuleRepository interface { //repo (secondary port)
+	FindAll() ([]IpAccessRule, *errs.AppError)
+	Save(IpAccessRule) (*IpAccessRule, *errs.AppError)
+	Delete(int64) *errs.AppError
+}
+
+type IpAccessRuleRepositoryDb struct { //DB (adapter)
+	client *sqlx.DB
+}
+
+func NewIpAccessRuleRepositoryDb(dbClient *sqlx.DB) IpAccessRuleRepositoryDb {
+	return IpAccessRuleRepositoryDb{dbClient}
+}
+
+func (d IpAccessRuleRepositoryDb) FindAll() ([]IpAccessRule, *errs.AppError) {
+	rules := make([]IpAccessRule, 0)
+	findSql := `SELECT rule_id, role, route_name, cidr, action, created_by, created_on FROM ip_access_rules 
+		ORDER BY rule_id`
+	if err := d.client.Select(&rules, findSql); err != nil {
+		logger.Error("Error while retrieving IP access rules: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+	return rules, nil
+}
+
+// Save adds the given rule, returning it with its ID.
+func (d IpAccessRuleRepositoryDb) Save(rule IpAccessRule) (*IpAccessRule, *errs.AppError) {
+	insertSql := `INSERT INTO ip_access_rules (role, route_name, cidr, action, created_by, created_on) 
+		VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := d.client.Exec(insertSql, rule.Role, rule.RouteName, rule.Cidr, rule.Action, rule.CreatedBy,
+		rule.DateCreated)
+	if err != nil {
+		logger.Error("Error while saving IP access rule: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if rule.Id, err = result.LastInsertId(); err != nil {
+		logger.Error("Error while retrieving id of saved IP access rule: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+	return &rule, nil
+}
+
+func (d IpAccessRuleRepositoryDb) Delete(ruleId int64) *errs.AppError {
+	result, err := d.client.Exec(`DELETE FROM ip_access_rules WHERE rule_id = ?`, ruleId)
+	if err != nil {
+		logger.Error("Error while deleting IP access rule: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if numDeleted, err := result.RowsAffected(); err != nil {
+		logger.Error("Error while checking deletion of IP access rule: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	} else if numDeleted == 0 {
+		logger.Error("IP access rule not found")
+		return errs.NewNotFoundError("Rule not found")
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+const KycDocumentMaxSize = 5 << 20 //5 MiB
+const KycStatusNotSubmitted = "not_submitted"
+const KycStatusSubmitted = "submitted"
+const KycStatusVerified = "verified"
+const KycStatusRejected = "rejected"
+
+// kycContentTypeExtensions maps the content types allowed for KYC documents to the file extensions to store them with.
+var kycContentTypeExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+// KycDocument is the metadata of an identity document uploaded for a Registration (know-your-customer check).
+// The content itself is kept in a blob store under StorageKey.
+type KycDocument struct { //business/domain object
+	DocumentId   string `db:"document_id"`
+	Email        string
+	DocumentType string `db:"document_type"`
+	FileName     string `db:"file_name"` //as given by the client, for display only
+	ContentType  string `db:"content_type"`
+	Size         int64  `db:"size_bytes"`
+	Checksum     string `db:"checksum"` //SHA-256, hex
+	StorageKey   string `db:"storage_key"`
+	DateUploaded string `db:"uploaded_on"`
+}
+
+// NewKycDocument validates the size and type of the given document content and creates a new KycDocument for it,
+// to be uploaded for the Registration made using the given email.
+func NewKycDocument(email string, documentType string, fileName string, content []byte) (*KycDocument, *errs.AppError) {
+	if len(content) == 0 || len(content) > KycDocumentMaxSize {
+		logger.Error(fmt.Sprintf("KYC document has invalid size (%d bytes)", len(content)))
+		return nil, errs.NewValidationError("File must not be empty and must be at most 5 MB")
+	}
+
+	//detect from the content itself instead of trusting the type declared by the client
+	contentType := http.DetectContentType(content)
+	extension, ok := kycContentTypeExtensions[contentType]
+	if !ok {
+		logger.Error("KYC document has type that is not allowed: " + contentType)
+		return nil, errs.NewValidationError("File must be a PDF, JPEG or PNG")
+	}
+
+	documentId, appErr := GenerateRandomId()
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return &KycDocument{
+		DocumentId:   documentId,
+		Email:        email,
+		DocumentType: documentType,
+		FileName:     filepath.Base(fileName),
+		ContentType:  contentType,
+		Size:         int64(len(content)),
+		Checksum:     getChecksum(content),
+		StorageKey:   "kyc/" + documentId + extension,
+		DateUploaded: time.Now().UTC().Format(FormatDateTime),
+	}, nil
+}
+
+// CheckIntegrity ensures that the given content retrieved from the blob store is the same as what was uploaded.
+func (d KycDocument) CheckIntegrity(content []byte) *errs.AppError {
+	if getChecksum(content) != d.Checksum {
+		logger.Error("Checksum mismatch for KYC document " + d.DocumentId)
+		return errs.NewUnexpectedError("Unexpected server-side error")
+	}
+	return nil
+}
+
+func (d KycDocument) ToDTO() dto.KycDocumentResponse {
+	return dto.KycDocumentResponse{
+		DocumentId:   d.DocumentId,
+		DocumentType: d.DocumentType,
+		FileName:     d.FileName,
+		ContentType:  d.ContentType,
+		Size:         d.Size,
+		Checksum:     d.Checksum,
+		DateUploaded: d.DateUploaded,
+	}
+}
+
+func getChecksum(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type KycRepository interface { //repo (secondary port)
+	Save(KycDocument) *errs.AppError
+	FindAll(string) ([]KycDocument, *errs.AppError)
+	Find(string, string) (*KycDocument, *errs.AppError)
+	UpdateStatus(string, string, string) *errs.AppError
+	FindOrphaned() ([]KycDocument, *errs.AppError)
+	Delete(string) *errs.AppError
+}
+
+type KycRepositoryDb struct { //DB (adapter)
+	client *sqlx.DB
+}
+
+func NewKycRepositoryDb(dbClient *sqlx.DB) KycRepositoryDb {
+	return KycRepositoryDb{dbClient}
+}
+
+// Save stores the metadata of the given KycDocument and updates the KYC status of its Registration to submitted,
+// in a single db transaction.
+func (d KycRepositoryDb) Save(doc KycDocument) *errs.AppError {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for saving KYC document: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	insertSql := `INSERT INTO kyc_documents 
+    (document_id, email, document_type, file_name, content_type, size_bytes, checksum, storage_key, uploaded_on) 
+    VALUES (?,?,?,?,?,?,?,?,?)`
+	_, err = tx.Exec(insertSql, doc.DocumentId, doc.Email, doc.DocumentType, doc.FileName, doc.ContentType,
+		doc.Size, doc.Checksum, doc.StorageKey, doc.DateUploaded)
+	if err != nil {
+		logger.Error("Error while saving KYC document: " + err.Error())
+		rollbackTx(tx, "saving of KYC document")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if _, err = tx.Exec("UPDATE registrations SET kyc_status = ? WHERE email = ?", KycStatusSubmitted, doc.Email); err != nil {
+		logger.Error("Error while updating KYC status of registration to submitted: " + err.Error())
+		rollbackTx(tx, "saving of KYC document")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for saving KYC document: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// FindAll retrieves the metadata of all KYC documents uploaded for the Registration made using the given email,
+// the earliest uploaded first.
+func (d KycRepositoryDb) FindAll(email string) ([]KycDocument, *errs.AppError) {
+	docs := make([]KycDocument, 0)
+	if err := d.client.Select(&docs, "SELECT * FROM kyc_documents WHERE email = ? ORDER BY uploaded_on", email); err != nil {
+		logger.Error("Error while retrieving KYC documents: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return docs, nil
+}
+
+// Find retrieves the metadata of the KYC document with the given ID, which must have been uploaded for the
+// Registration made using the given email.
+func (d KycRepositoryDb) Find(email string, documentId string) (*KycDocument, *errs.AppError) {
+	var doc KycDocument
+	findSql := "SELECT * FROM kyc_documents WHERE email = ? AND document_id = ?"
+	if err := d.client.Get(&doc, findSql, email, documentId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("The given KYC document does not exist")
+			return nil, errs.NewNotFoundError("Document not found")
+		}
+		logger.Error("Error while retrieving KYC document: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return &doc, nil
+}
+
+// UpdateStatus updates the KYC status of the Registration made using the given email after its documents have been
+// checked, along with the reason given by the reviewer if any. Documents must have been submitted for it.
+func (d KycRepositoryDb) UpdateStatus(email string, status string, reason string) *errs.AppError {
+	updateSql := "UPDATE registrations SET kyc_status = ?, kyc_review_reason = ? WHERE email = ? AND kyc_status = ?"
+	result, err := d.client.Exec(updateSql, status, sql.NullString{String: reason, Valid: reason != ""}, email, KycStatusSubmitted)
+	if err != nil {
+		logger.Error("Error while updating KYC status of registration: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	rowsUpdated, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Error while checking that KYC status of registration was updated: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	if rowsUpdated != 1 {
+		logger.Error("Registration does not exist or has no KYC documents waiting to be checked")
+		return errs.NewConflictError("No KYC documents waiting to be checked for this registration")
+	}
+
+	return nil
+}
+
+// FindOrphaned retrieves the metadata of KYC documents whose Registration no longer exists, e.g. because it expired.
+func (d KycRepositoryDb) FindOrphaned() ([]KycDocument, *errs.AppError) {
+	docs := make([]KycDocument, 0)
+	findSql := `SELECT * FROM kyc_documents d 
+		WHERE NOT EXISTS (SELECT 1 FROM registrations r WHERE r.email = d.email)`
+	if err := d.client.Select(&docs, findSql); err != nil {
+		logger.Error("Error while retrieving orphaned KYC documents: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return docs, nil
+}
+
+// Delete removes the metadata of the KYC document with the given ID.
+func (d KycRepositoryDb) Delete(documentId string) *errs.AppError {
+	if _, err := d.client.Exec("DELETE FROM kyc_documents WHERE document_id = ?", documentId); err != nil {
+		logger.Error("Error while deleting KYC document: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
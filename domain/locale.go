@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"embed"
+	"encoding/json"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"golang.org/x/text/language"
+	"path"
+)
+
+const DefaultLocale = "en"
+
+// SupportedLocales lists the locales that emails and messages are available in, with DefaultLocale first so that
+// it is used when none of the others match.
+var SupportedLocales = []string{DefaultLocale, "fr"}
+
+var localeMatcher = newLocaleMatcher()
+
+//go:embed templates/messages
+var messageCatalogueFiles embed.FS
+
+// messageCatalogues maps each supported locale (other than DefaultLocale, which the messages are written in) to the
+// translations of the messages returned in responses, keyed by the original message.
+var messageCatalogues = loadMessageCatalogues()
+
+func newLocaleMatcher() language.Matcher {
+	tags := make([]language.Tag, 0, len(SupportedLocales))
+	for _, l := range SupportedLocales {
+		tags = append(tags, language.MustParse(l))
+	}
+	return language.NewMatcher(tags)
+}
+
+func loadMessageCatalogues() map[string]map[string]string {
+	catalogues := make(map[string]map[string]string)
+	for _, l := range SupportedLocales[1:] {
+		content, err := messageCatalogueFiles.ReadFile(path.Join("templates/messages", l+".json"))
+		if err != nil {
+			logger.Fatal("Error while reading message catalogue: " + err.Error())
+		}
+		catalogue := make(map[string]string)
+		if err = json.Unmarshal(content, &catalogue); err != nil {
+			logger.Fatal("Error while parsing message catalogue: " + err.Error())
+		}
+		catalogues[l] = catalogue
+	}
+	return catalogues
+}
+
+// NegotiateLocale returns the supported locale that best matches the languages in the given Accept-Language header
+// value, taking their quality values into account. Regional variants fall back to their base language (e.g. fr-CA
+// to fr), and DefaultLocale is returned if nothing matches or the header is missing or malformed.
+func NegotiateLocale(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, i, confidence := localeMatcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return SupportedLocales[i]
+}
+
+// NormaliseLocale returns the supported locale that best matches the given language tag, following the same fallback
+// rules as NegotiateLocale.
+func NormaliseLocale(locale string) string {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return DefaultLocale
+	}
+	_, i, confidence := localeMatcher.Match(tag)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return SupportedLocales[i]
+}
+
+// TranslateMessage returns the given message in the given locale, or the message unchanged if it has no translation
+// (which includes the token-related messages that clients act on, e.g. errs.MessageExpiredAccessToken).
+func TranslateMessage(locale string, msg string) string {
+	if translated, ok := messageCatalogues[locale][msg]; ok {
+		return translated
+	}
+	return msg
+}
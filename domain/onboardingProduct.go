@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"database/sql"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// OnboardingProduct is a bank account to be opened for a newly-registered customer. Products can be scoped to a
+// country and/or an onboarding option chosen during registration; products with neither apply by default.
+type OnboardingProduct struct { //business/domain object
+	AccountType      string         `db:"account_type"`
+	Amount           float64        `db:"opening_amount"`
+	Status           string         `db:"status"`
+	CountryCode      sql.NullString `db:"country_code"`
+	OnboardingOption sql.NullString `db:"onboarding_option"`
+}
+
+// getSpecificity ranks how specifically the OnboardingProduct is scoped, where a product for an onboarding option is
+// more specific than a product for a country.
+func (p OnboardingProduct) getSpecificity() int {
+	specificity := 0
+	if p.OnboardingOption.Valid {
+		specificity += 2
+	}
+	if p.CountryCode.Valid {
+		specificity += 1
+	}
+	return specificity
+}
+
+// findOnboardingProducts retrieves, within the given transaction, the active products to open accounts with for the
+// given Registration. Only the products in the most specific scope that matches the registration are used, in this
+// order: its onboarding option and country, its onboarding option, its country, then the default. There may be no
+// products at all, in which case no accounts are opened.
+func findOnboardingProducts(tx *sqlx.Tx, reg *Registration) ([]OnboardingProduct, *errs.AppError) {
+	candidates := make([]OnboardingProduct, 0)
+	findSql := `SELECT account_type, opening_amount, status, country_code, onboarding_option FROM onboarding_products 
+		WHERE is_active = 1 AND (onboarding_option IS NULL OR onboarding_option = ?) AND (country_code IS NULL OR country_code = ?) 
+		ORDER BY product_id`
+	if err := tx.Select(&candidates, findSql, reg.OnboardingOption, reg.CountryCode); err != nil {
+		logger.Error("Error while retrieving onboarding products: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	maxSpecificity := -1
+	for _, p := range candidates {
+		if p.getSpecificity() > maxSpecificity {
+			maxSpecificity = p.getSpecificity()
+		}
+	}
+
+	products := make([]OnboardingProduct, 0)
+	for _, p := range candidates {
+		if p.getSpecificity() == maxSpecificity {
+			products = append(products, p)
+		}
+	}
+	return products, nil
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"database/sql"
+	"github.com/aliciatay-zls/banking-auth/dto"
+)
+
+// OneTimeToken is the record of a one-time token issued in a link emailed to a client.
+type OneTimeToken struct { //business/domain object
+	TokenId         string         `db:"token_id"`
+	Email           string         `db:"email"`
+	Purpose         string         `db:"purpose"`
+	DateCreated     string         `db:"created_on"`
+	DateExpires     string         `db:"expires_on"`
+	DateUsed        sql.NullString `db:"used_on"`
+	DateInvalidated sql.NullString `db:"invalidated_on"`
+}
+
+func (t OneTimeToken) ToExportDTO() dto.OneTimeTokenExport {
+	return dto.OneTimeTokenExport{
+		Email:           t.Email,
+		Purpose:         t.Purpose,
+		DateSent:        t.DateCreated,
+		DateExpires:     t.DateExpires,
+		DateUsed:        t.DateUsed.String,
+		DateInvalidated: t.DateInvalidated.String,
+	}
+}
@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+const OneTimeTokenPurposeRegistration = "registration"
+const OneTimeTokenPurposeEmailChange = "email_change"
+const OneTimeTokenPurposeDataExport = "data_export"
+const OneTimeTokenPurposeInvitation = "invitation"
+
+type OneTimeTokenRepository interface { //repo (secondary port)
+	Save(string, string, string, time.Time, ...OutboxEmail) *errs.AppError
+	CheckUsable(string) *errs.AppError
+	MarkUsed(string) *errs.AppError
+	FindAllFromEmail(string) ([]OneTimeToken, *errs.AppError)
+}
+
+type OneTimeTokenRepositoryDb struct { //DB (adapter)
+	client *sqlx.DB
+}
+
+func NewOneTimeTokenRepositoryDb(dbClient *sqlx.DB) OneTimeTokenRepositoryDb {
+	return OneTimeTokenRepositoryDb{dbClient}
+}
+
+// Save records a newly-issued one-time token with the given ID, sent to the given email for the given purpose and
+// expiring at the given time. Any older one-time tokens issued for the same email and purpose that have not been used
+// yet are invalidated in the same transaction, so only the latest link works. The given OutboxEmails (e.g. the one
+// delivering the link) are added to the outbox in the same transaction and linked to the token, so they are only sent
+// if the token was saved.
+func (d OneTimeTokenRepositoryDb) Save(tokenId string, email string, purpose string, expiresAt time.Time, emails ...OutboxEmail) *errs.AppError {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for saving one-time token: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if err = saveOneTimeToken(tx, tokenId, email, purpose, expiresAt); err != nil {
+		logger.Error("Error while saving one-time token: " + err.Error())
+		rollbackTx(tx, "saving of one-time token")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	for _, e := range emails {
+		if err = enqueueEmail(tx, e.ForToken(tokenId)); err != nil {
+			logger.Error("Error while enqueueing email of one-time token: " + err.Error())
+			rollbackTx(tx, "saving of one-time token")
+			return errs.NewUnexpectedError("Unexpected database error")
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for saving one-time token: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// saveOneTimeToken invalidates the older one-time tokens and records the new one as described in Save. It is shared
+// with other repos so that the token can be saved as part of their transactions.
+func saveOneTimeToken(e sqlx.Execer, tokenId string, email string, purpose string, expiresAt time.Time) error {
+	now := time.Now().UTC().Format(FormatDateTime)
+
+	invalidateSql := `UPDATE one_time_tokens SET invalidated_on = ? 
+		WHERE email = ? AND purpose = ? AND used_on IS NULL AND invalidated_on IS NULL`
+	if _, err := e.Exec(invalidateSql, now, email, purpose); err != nil {
+		return err
+	}
+
+	insertSql := "INSERT INTO one_time_tokens (token_id, email, purpose, created_on, expires_on) VALUES (?, ?, ?, ?, ?)"
+	_, err := e.Exec(insertSql, tokenId, email, purpose, now, expiresAt.UTC().Format(FormatDateTime))
+	return err
+}
+
+// CheckUsable checks that the one-time token with the given ID was issued by this server and has neither been used
+// nor invalidated by a newer one-time token.
+func (d OneTimeTokenRepositoryDb) CheckUsable(tokenId string) *errs.AppError {
+	var token struct {
+		UsedOn        sql.NullString `db:"used_on"`
+		InvalidatedOn sql.NullString `db:"invalidated_on"`
+	}
+	findSql := "SELECT used_on, invalidated_on FROM one_time_tokens WHERE token_id = ?"
+	if err := d.client.Get(&token, findSql, tokenId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("One-time token was not issued by this server")
+			return errs.NewAuthenticationError("Invalid OTT")
+		}
+		logger.Error("Error while checking if one-time token is usable: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if token.UsedOn.Valid {
+		logger.Error("One-time token was already used")
+		return errs.NewAuthenticationError("used OTT")
+	}
+	if token.InvalidatedOn.Valid {
+		logger.Error("One-time token was invalidated by a newer one")
+		return errs.NewAuthenticationError("invalidated OTT")
+	}
+
+	return nil
+}
+
+// MarkUsed records that the one-time token with the given ID has been used. This is done atomically so that when
+// several requests try to use the same token at the same time, only one of them succeeds.
+func (d OneTimeTokenRepositoryDb) MarkUsed(tokenId string) *errs.AppError {
+	return markOneTimeTokenUsed(d.client, tokenId)
+}
+
+// FindAllFromEmail retrieves the records of all one-time tokens issued to the given email, the latest first.
+func (d OneTimeTokenRepositoryDb) FindAllFromEmail(email string) ([]OneTimeToken, *errs.AppError) {
+	tokens := make([]OneTimeToken, 0)
+	findSql := `SELECT token_id, email, purpose, created_on, expires_on, used_on, invalidated_on FROM one_time_tokens 
+		WHERE email = ? ORDER BY created_on DESC`
+	if err := d.client.Select(&tokens, findSql, email); err != nil {
+		logger.Error("Error while retrieving one-time tokens: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return tokens, nil
+}
+
+// markOneTimeTokenUsed is shared with other repos so that using up a one-time token can be part of their transactions.
+func markOneTimeTokenUsed(e sqlx.Execer, tokenId string) *errs.AppError {
+	updateSql := "UPDATE one_time_tokens SET used_on = ? WHERE token_id = ? AND used_on IS NULL AND invalidated_on IS NULL"
+	result, err := e.Exec(updateSql, time.Now().UTC().Format(FormatDateTime), tokenId)
+	if err != nil {
+		logger.Error("Error while marking one-time token as used: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	rowsUpdated, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Error while checking that one-time token was marked as used: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	if rowsUpdated != 1 {
+		logger.Error("One-time token is unknown, already used or invalidated")
+		return errs.NewAuthenticationError("used or invalidated OTT")
+	}
+
+	return nil
+}
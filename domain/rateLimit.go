@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const RateLimitKeyIp = "ip"
+const RateLimitKeySubnet = "subnet"      //IPv4 /24 or IPv6 /64 of the client IP, e.g. to catch address rotation
+const RateLimitKeyUsername = "username"  //username field of the JSON body
+const RateLimitKeyEmail = "email"        //email field of the JSON body
+const RateLimitKeyClientIp = "client_ip" //client_ip query parameter, the IP address of a client of the banking server
+const RateLimitSubnetBitsIpv4 = 24
+const RateLimitSubnetBitsIpv6 = 64
+
+// RateLimitRule allows at most Limit requests per Period for each value of the dimension given by Key, e.g. for each
+// IP address. Requests are counted with a token bucket, so Limit requests can be made at once before being spread out.
+type RateLimitRule struct {
+	Key    string
+	Limit  int
+	Period time.Duration
+}
+
+// RateLimitPolicy holds the rules applying to each route, by route name. Every rule of a route must allow a request
+// for it to go through, and it only counts against the rules if it does. Routes without rules are not limited.
+type RateLimitPolicy map[string][]RateLimitRule
+
+// DefaultRateLimitPolicy is used for the routes not configured in the file given by RATE_LIMITS_PATH.
+var DefaultRateLimitPolicy = RateLimitPolicy{
+	"Login": {
+		{RateLimitKeyIp, 1, time.Second},
+		{RateLimitKeySubnet, 30, time.Minute},
+		{RateLimitKeyUsername, 10, time.Minute * 15},
+	},
+	"Register": {
+		{RateLimitKeyIp, 1, time.Second},
+		{RateLimitKeySubnet, 10, time.Minute},
+		{RateLimitKeyEmail, 5, time.Hour},
+	},
+	"ResendLink": {
+		{RateLimitKeyIp, 1, time.Second},
+		{RateLimitKeyEmail, 3, time.Minute},
+	},
+	"EmailChange": { //sends emails to an address of the client's choosing
+		{RateLimitKeyIp, 1, time.Second},
+		{RateLimitKeyIp, 5, time.Hour},
+		{RateLimitKeySubnet, 20, time.Hour},
+	},
+	"Refresh": {
+		{RateLimitKeyIp, 10, time.Minute},
+	},
+	"Verify": { //called by the banking server for each of its requests, so limited per client of the banking server
+		{RateLimitKeyClientIp, 20, time.Second},
+	},
+}
+
+type rateLimitRuleJson struct {
+	Key    string `json:"key"`
+	Limit  int    `json:"limit"`
+	Period string `json:"period"`
+}
+
+// NewRateLimitPolicy returns the DefaultRateLimitPolicy, with the rules of the routes configured in the JSON file
+// given by the RATE_LIMITS_PATH environment variable (if set) replacing the default ones. The file maps route names
+// to lists of rules, e.g. {"Login": [{"key": "ip", "limit": 5, "period": "1m"}]}.
+func NewRateLimitPolicy() RateLimitPolicy {
+	policy := make(RateLimitPolicy)
+	for route, rules := range DefaultRateLimitPolicy {
+		policy[route] = rules
+	}
+
+	path := os.Getenv("RATE_LIMITS_PATH")
+	if path == "" {
+		return policy
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		logger.Fatal("Error while reading rate limits: " + err.Error())
+	}
+	configured := make(map[string][]rateLimitRuleJson)
+	if err = json.Unmarshal(content, &configured); err != nil {
+		logger.Fatal("Error while parsing rate limits: " + err.Error())
+	}
+
+	for route, rules := range configured {
+		policy[route] = make([]RateLimitRule, 0)
+		for _, r := range rules {
+			rule, err := newRateLimitRule(r)
+			if err != nil {
+				logger.Fatal(fmt.Sprintf("Invalid rate limit for route %s: %s", route, err.Error()))
+			}
+			policy[route] = append(policy[route], rule)
+		}
+	}
+	return policy
+}
+
+func newRateLimitRule(r rateLimitRuleJson) (RateLimitRule, error) {
+	switch r.Key {
+	case RateLimitKeyIp, RateLimitKeySubnet, RateLimitKeyUsername, RateLimitKeyEmail, RateLimitKeyClientIp:
+	default:
+		return RateLimitRule{}, fmt.Errorf("unknown key %s", r.Key)
+	}
+	if r.Limit <= 0 {
+		return RateLimitRule{}, fmt.Errorf("limit must be positive")
+	}
+	period, err := time.ParseDuration(r.Period)
+	if err != nil || period <= 0 {
+		return RateLimitRule{}, fmt.Errorf("period must be a positive duration")
+	}
+
+	return RateLimitRule{Key: r.Key, Limit: r.Limit, Period: period}, nil
+}
+
+// IsRateLimitedByBody checks whether any of the given rules is keyed on a field of the request body.
+func IsRateLimitedByBody(rules []RateLimitRule) bool {
+	for _, rule := range rules {
+		if rule.Key == RateLimitKeyUsername || rule.Key == RateLimitKeyEmail {
+			return true
+		}
+	}
+	return false
+}
+
+// NormaliseRateLimitIp returns the given IP address in its canonical form, so that different spellings of the same
+// address share the same budget. An empty string is returned if it is not an IP address.
+func NormaliseRateLimitIp(ip string) string {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return ""
+	}
+	return parsed.String()
+}
+
+// GetRateLimitSubnet returns the subnet of the given IP address that is rate-limited as a whole, in CIDR notation.
+func GetRateLimitSubnet(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	mask := net.CIDRMask(RateLimitSubnetBitsIpv6, 128)
+	if ipv4 := parsed.To4(); ipv4 != nil {
+		parsed, mask = ipv4, net.CIDRMask(RateLimitSubnetBitsIpv4, 32)
+	}
+	return (&net.IPNet{IP: parsed.Mask(mask), Mask: mask}).String()
+}
+
+// NormaliseRateLimitValue makes values of the same dimension that differ only in case or surrounding whitespace share
+// the same budget, e.g. usernames and emails.
+func NormaliseRateLimitValue(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/aliciatay-zls/banking-lib/formValidator"
 	"github.com/aliciatay-zls/banking-lib/logger"
 	"github.com/golang-jwt/jwt/v5"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -14,8 +16,12 @@ import (
 const FormatDateTime = "2006-01-02 15:04:05" //time.DateTime
 const ResendEmailAllowedAttempts = 11        //additional 1 attempt since during registration an email is already sent
 const ResendEmailAllowedInterval = time.Minute
-const RetrySendEmailAttempts = 3
-const RetrySendEmailInterval = time.Second * 5
+const ResendEmailAttemptsWindow = time.Hour * 24
+const DefaultRegistrationExpiry = time.Hour * 24 * 7
+const RegistrationCleanupInterval = time.Minute * 10
+const RegistrationStatusConfirmed = "1"
+const RegistrationStatusPendingReview = "2"
+const RegistrationStatusRejected = "3"
 
 type Registration struct { //business/domain object
 	Email       string
@@ -23,29 +29,46 @@ type Registration struct { //business/domain object
 	Name        string
 	DateOfBirth string `db:"date_of_birth"` //yyyy-mm-dd
 	Country     string
+	CountryCode sql.NullString `db:"country_code"` //ISO 3166-1 alpha-2, not recorded for older registrations
 	Zipcode     string
 	Status      string
+	Locale      string //preferred language of emails, one of SupportedLocales
+
+	OnboardingOption sql.NullString `db:"onboarding_option"` //determines the accounts opened along with CountryCode
+	KycStatus        string         `db:"kyc_status"`
+	KycReviewReason  sql.NullString `db:"kyc_review_reason"`
 
 	Username       string
 	HashedPassword string `db:"password"`
 	Role           string
 
-	EmailAttempts int `db:"email_attempts"` //to reset daily using pipeline
+	EmailAttempts    int            `db:"email_attempts"`     //within the window starting from EmailWindowStart
+	EmailWindowStart sql.NullString `db:"email_window_start"` //reset once ResendEmailAttemptsWindow has passed
+
+	DateRegistered    string         `db:"created_on"`
+	DateLastEmailed   string         `db:"last_emailed_on"`
+	DateEmailVerified sql.NullString `db:"email_verified_on"`
+	DateConfirmed     sql.NullString `db:"confirmed_on"`
 
-	DateRegistered  string         `db:"created_on"`
-	DateLastEmailed string         `db:"last_emailed_on"`
-	DateConfirmed   sql.NullString `db:"confirmed_on"`
+	ReviewedBy   sql.NullString `db:"reviewed_by"` //username of the admin who approved or rejected the registration
+	DateReviewed sql.NullString `db:"reviewed_on"`
+	ReviewReason sql.NullString `db:"review_reason"`
 }
 
-// NewRegistration creates a new Registration object, filling all fields except CustomerId, Status and DateConfirmed,
-// each of which have default values in the db and are to be initialized at a later step through Confirm().
+// NewRegistration creates a new Registration object, filling all fields except CustomerId, Status, the dates of
+// verification and confirmation and the review information, each of which have default values in the db and are to
+// be initialized at a later step through Confirm(), SubmitForReview() or Reject().
 func NewRegistration(req dto.RegistrationRequest, hashedPw string) Registration {
 	return Registration{
 		Email:       req.Email,
 		Name:        strings.Join([]string{req.FirstName, req.LastName}, " "),
 		DateOfBirth: req.DateOfBirth,
 		Country:     formValidator.GetCountryFrom(req.CountryCode),
+		CountryCode: sql.NullString{String: req.CountryCode, Valid: true},
 		Zipcode:     req.Zipcode,
+		Locale:      NormaliseLocale(req.Locale),
+
+		OnboardingOption: sql.NullString{String: req.OnboardingOption, Valid: req.OnboardingOption != ""},
 
 		Username:       req.Username,
 		HashedPassword: hashedPw,
@@ -62,47 +85,300 @@ func (r Registration) ToDTO() *dto.RegistrationResponse {
 	}
 }
 
-func (r Registration) CanResendEmail() *errs.AppError {
+// CanResendEmail checks that the confirmation link of the Registration is still needed and that resending it now is
+// within the limits on resending. It returns the state of those limits if they were checked.
+func (r Registration) CanResendEmail() (*dto.RateLimitStatus, *errs.AppError) {
+	if appErr := r.CanForceResendEmail(); appErr != nil {
+		return nil, appErr
+	}
+
+	status, appErr := checkEmailAttempts(r.EmailAttempts, r.EmailWindowStart, r.DateLastEmailed)
+	return &status, appErr
+}
+
+// CanForceResendEmail checks that the confirmation link of the Registration is still needed, without the limits on
+// resending that CanResendEmail also checks, for an admin to resend it regardless.
+func (r Registration) CanForceResendEmail() *errs.AppError {
 	if r.IsConfirmed() {
 		logger.Error("Cannot resend email as registration is already confirmed")
 		return errs.NewValidationError("Already confirmed")
 	}
 
-	if r.EmailAttempts >= ResendEmailAllowedAttempts {
+	if r.IsEmailVerified() {
+		logger.Error("Cannot resend email as email of registration is already verified")
+		return errs.NewValidationError("Email already verified")
+	}
+
+	return nil
+}
+
+// checkEmailAttempts ensures that another email can be sent given the number of emails already sent within the
+// window starting from windowStart and the time the last one was sent, i.e. that neither the maximum attempts within
+// the window nor the minimum interval between attempts are exceeded. It returns the state of the daily limit, with
+// the email about to be sent counted if it is allowed.
+func checkEmailAttempts(attempts int, windowStart sql.NullString, lastEmailedStr string) (dto.RateLimitStatus, *errs.AppError) {
+	now := time.Now().UTC()
+	status := dto.RateLimitStatus{Limit: ResendEmailAllowedAttempts}
+	attemptsInWindow := getEmailAttemptsInWindow(attempts, windowStart)
+	if attemptsInWindow > 0 {
+		windowStartTime, _ := time.Parse(FormatDateTime, windowStart.String) //alr parsed by getEmailAttemptsInWindow
+		status.Reset = windowStartTime.Add(ResendEmailAttemptsWindow).Sub(now)
+	}
+
+	if attemptsInWindow >= ResendEmailAllowedAttempts {
 		logger.Error("Cannot resend email as maximum daily attempts reached")
-		return errs.NewValidationError("Maximum daily attempts reached")
+		status.RetryAfter = status.Reset
+		return status, errs.NewValidationError("Maximum daily attempts reached")
 	}
 
-	lastEmailed, err := time.Parse(FormatDateTime, r.DateLastEmailed)
+	lastEmailed, err := time.Parse(FormatDateTime, lastEmailedStr)
 	if err != nil {
 		logger.Error("Cannot resend email due to error while parsing time last emailed: " + err.Error())
-		return errs.NewUnexpectedError("Unexpected server-side error")
+		return status, errs.NewUnexpectedError("Unexpected server-side error")
 	}
-	if time.Now().UTC().Sub(lastEmailed) <= ResendEmailAllowedInterval { //no need lastEmailed.UTC(): alr stored in UTC
+	if now.Sub(lastEmailed) <= ResendEmailAllowedInterval { //no need lastEmailed.UTC(): alr stored in UTC
 		logger.Error("Cannot resend email as attempts made are too frequent")
-		return errs.NewValidationError("Too many attempts")
+		status.Remaining = ResendEmailAllowedAttempts - attemptsInWindow
+		status.RetryAfter = lastEmailed.Add(ResendEmailAllowedInterval).Sub(now) + time.Second //interval is inclusive
+		return status, errs.NewValidationError("Too many attempts")
 	}
 
+	status.IsAllowed = true
+	status.Remaining = ResendEmailAllowedAttempts - attemptsInWindow - 1
+	if attemptsInWindow == 0 { //this email starts a new window
+		status.Reset = ResendEmailAttemptsWindow
+	}
+	return status, nil
+}
+
+// IsExpired checks whether the Registration was left unconfirmed for longer than the expiry returned by
+// GetRegistrationExpiry. Registrations that are confirmed or have had their email verified never expire.
+func (r Registration) IsExpired() bool {
+	if r.IsEmailVerified() {
+		return false
+	}
+
+	registered, err := time.Parse(FormatDateTime, r.DateRegistered)
+	if err != nil {
+		logger.Error("Error while parsing time registered, treating registration as expired: " + err.Error())
+		return true
+	}
+	return time.Now().UTC().Sub(registered) > GetRegistrationExpiry()
+}
+
+// CheckExpiry ensures that the Registration has not expired as it would be removed soon and cannot be used anymore.
+func (r Registration) CheckExpiry() *errs.AppError {
+	if r.IsExpired() {
+		logger.Error("Expired registration")
+		return errs.NewNotFoundError("Registration expired")
+	}
 	return nil
 }
 
+// getEmailAttemptsInWindow returns the number of emails sent within the rolling window of ResendEmailAttemptsWindow
+// that started from the first email sent in it. Once the window has passed, no attempts are counted until the next
+// email sent starts a new window.
+func getEmailAttemptsInWindow(attempts int, windowStartStr sql.NullString) int {
+	if !windowStartStr.Valid {
+		return 0
+	}
+
+	windowStart, err := time.Parse(FormatDateTime, windowStartStr.String)
+	if err != nil {
+		logger.Error("Error while parsing start of email attempts window, treating it as passed: " + err.Error())
+		return 0
+	}
+	if time.Now().UTC().Sub(windowStart) >= ResendEmailAttemptsWindow {
+		return 0
+	}
+	return attempts
+}
+
 func (r Registration) IsConfirmed() bool {
-	return r.CustomerId.Valid && r.Status == "1" && r.DateConfirmed.Valid
+	return r.CustomerId.Valid && r.Status == RegistrationStatusConfirmed && r.DateConfirmed.Valid
+}
+
+func (r Registration) IsPendingReview() bool {
+	return r.Status == RegistrationStatusPendingReview
+}
+
+func (r Registration) IsRejected() bool {
+	return r.Status == RegistrationStatusRejected
+}
+
+// IsEmailVerified checks whether the confirmation link sent to the email of the Registration was used. Registrations
+// confirmed before verification was recorded separately only have the date confirmed.
+func (r Registration) IsEmailVerified() bool {
+	return r.DateEmailVerified.Valid || r.IsConfirmed()
+}
+
+func (r Registration) IsKycVerified() bool {
+	return r.KycStatus == KycStatusVerified
+}
+
+// CanUploadKycDocuments checks that identity documents can still be uploaded for the Registration: it must not be
+// expired, confirmed or rejected, and its documents must not have been verified already.
+func (r Registration) CanUploadKycDocuments() *errs.AppError {
+	if appErr := r.CheckExpiry(); appErr != nil {
+		return appErr
+	}
+
+	if r.IsConfirmed() || r.IsRejected() {
+		logger.Error("Cannot upload KYC documents for registration that is already confirmed or rejected")
+		return errs.NewValidationError(r.GetStatusMessage())
+	}
+
+	if r.IsKycVerified() {
+		logger.Error("Cannot upload KYC documents for registration whose documents are already verified")
+		return errs.NewValidationError("Documents already verified")
+	}
+
+	return nil
+}
+
+// GetStatusMessage describes to the client a Registration that can no longer go on to be confirmed using a
+// confirmation link. It returns an empty string otherwise.
+func (r Registration) GetStatusMessage() string {
+	if r.IsConfirmed() {
+		return "Registration already confirmed"
+	}
+	if r.IsPendingReview() {
+		return "Registration pending review"
+	}
+	if r.IsRejected() {
+		return "Registration rejected"
+	}
+	return ""
 }
 
 func (r Registration) Confirm(id string, date string) *Registration {
-	r.CustomerId = sql.NullString{String: id}
-	r.Status = "1"
-	r.DateConfirmed = sql.NullString{String: date}
+	r.CustomerId = sql.NullString{String: id, Valid: true}
+	r.Status = RegistrationStatusConfirmed
+	r.DateConfirmed = sql.NullString{String: date, Valid: true}
+	if !r.DateEmailVerified.Valid {
+		r.DateEmailVerified = sql.NullString{String: date, Valid: true}
+	}
+	return &r
+}
+
+// SubmitForReview records that the email of the Registration has been verified, and that it now needs to be approved
+// by an admin before it can be confirmed.
+func (r Registration) SubmitForReview(date string) *Registration {
+	r.Status = RegistrationStatusPendingReview
+	r.DateEmailVerified = sql.NullString{String: date, Valid: true}
 	return &r
 }
 
-func (r Registration) GetOneTimeTokenClaims() OneTimeTokenClaims {
-	return OneTimeTokenClaims{
+// Review records the admin who approved or rejected the Registration and the reason given, if any.
+func (r Registration) Review(reviewer string, reason string, date string) *Registration {
+	r.ReviewedBy = sql.NullString{String: reviewer, Valid: true}
+	r.DateReviewed = sql.NullString{String: date, Valid: true}
+	r.ReviewReason = sql.NullString{String: reason, Valid: reason != ""}
+	return &r
+}
+
+func (r Registration) Reject() *Registration {
+	r.Status = RegistrationStatusRejected
+	return &r
+}
+
+func (r Registration) ToPendingReviewDTO() dto.PendingRegistrationResponse {
+	return dto.PendingRegistrationResponse{
+		Email:             r.Email,
+		Name:              r.Name,
+		DateOfBirth:       r.DateOfBirth,
+		Country:           r.Country,
+		Zipcode:           r.Zipcode,
+		Username:          r.Username,
+		DateRegistered:    r.DateRegistered,
+		DateEmailVerified: r.DateEmailVerified.String,
+		KycStatus:         r.KycStatus,
+	}
+}
+
+// GetOneTimeTokenClaims creates the claims for a new one-time token for this Registration, with a random unique ID
+// so that the token can be tracked and used only once.
+func (r Registration) GetOneTimeTokenClaims() (*OneTimeTokenClaims, *errs.AppError) {
+	tokenId, err := GenerateRandomId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &OneTimeTokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenId,
 			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(OneTimeTokenDuration)),
 		},
 		Email:          r.Email,
 		DateRegistered: r.DateRegistered,
+	}, nil
+}
+
+// GetRegistrationExpiry returns how long an unconfirmed Registration is kept before it is removed and its username
+// and email are released. This is configured using the REGISTRATION_EXPIRY environment variable (a duration string
+// such as "72h"), and defaults to DefaultRegistrationExpiry if not set.
+func GetRegistrationExpiry() time.Duration {
+	val := os.Getenv("REGISTRATION_EXPIRY")
+	if val == "" {
+		return DefaultRegistrationExpiry
+	}
+
+	expiry, err := time.ParseDuration(val)
+	if err != nil || expiry <= 0 {
+		logger.Error("Invalid registration expiry, using default instead")
+		return DefaultRegistrationExpiry
+	}
+	return expiry
+}
+
+// IsRegistrationApprovalRequired checks whether new registrations need to be approved by an admin before accounts are
+// opened for them, which is configured using the REGISTRATION_APPROVAL_REQUIRED environment variable.
+func IsRegistrationApprovalRequired() bool {
+	isRequired, err := strconv.ParseBool(os.Getenv("REGISTRATION_APPROVAL_REQUIRED"))
+	return err == nil && isRequired
+}
+
+// IsKycRequired checks whether the identity documents of registrations pending review must be verified before they
+// can be approved, which is configured using the KYC_REQUIRED environment variable.
+func IsKycRequired() bool {
+	isRequired, err := strconv.ParseBool(os.Getenv("KYC_REQUIRED"))
+	return err == nil && isRequired
+}
+
+func (r Registration) ToExportDTO() *dto.RegistrationExport {
+	return &dto.RegistrationExport{
+		Email:             r.Email,
+		Name:              r.Name,
+		DateOfBirth:       r.DateOfBirth,
+		Country:           r.Country,
+		Zipcode:           r.Zipcode,
+		Locale:            r.Locale,
+		Username:          r.Username,
+		OnboardingOption:  r.OnboardingOption.String,
+		KycStatus:         r.KycStatus,
+		DateRegistered:    r.DateRegistered,
+		DateLastEmailed:   r.DateLastEmailed,
+		DateEmailVerified: r.DateEmailVerified.String,
+		DateConfirmed:     r.DateConfirmed.String,
+		DateReviewed:      r.DateReviewed.String,
+		ReviewReason:      r.ReviewReason.String,
+	}
+}
+
+// ForcedResend is the record of a confirmation link resent by an admin regardless of the limits on resending.
+type ForcedResend struct { //business/domain object
+	Id         int64  `db:"resend_id"`
+	Email      string `db:"email"`
+	ResentBy   string `db:"resent_by"`
+	Reason     string `db:"reason"`
+	DateResent string `db:"resent_on"`
+}
+
+func (f ForcedResend) ToExportDTO() dto.ForcedResendExport {
+	return dto.ForcedResendExport{
+		Email:      f.Email,
+		ResentBy:   f.ResentBy,
+		Reason:     f.Reason,
+		DateResent: f.DateResent,
 	}
 }
@@ -8,17 +8,25 @@ import (
 	"github.com/aliciatay-zls/banking-lib/logger"
 	"github.com/jmoiron/sqlx"
 	"strconv"
+	"time"
 )
 
 type RegistrationRepository interface { //repo (secondary port)
 	IsEmailUsed(string) *errs.AppError
 	IsUsernameTaken(string) *errs.AppError
-	Save(Registration) *errs.AppError
-	UpdateLastEmailedInfo(Registration, string) *errs.AppError
+	IsOnboardingOptionValid(string) *errs.AppError
+	Save(Registration, *OneTimeTokenClaims, OutboxEmail) *errs.AppError
+	EnqueueConfirmationLink(Registration, *OneTimeTokenClaims, OutboxEmail) *errs.AppError
+	ForceEnqueueConfirmationLink(Registration, *OneTimeTokenClaims, OutboxEmail, string, string) *errs.AppError
+	FindForcedResends(string) ([]ForcedResend, *errs.AppError)
 	FindFromLoginDetails(string, string) (*Registration, *errs.AppError)
 	FindFromEmail(string) (*Registration, *errs.AppError)
-	CreateNecessaryAccounts(*Registration, string) (string, *errs.AppError)
-	Update(*Registration) *errs.AppError
+	Confirm(string, string, string) (*Registration, bool, *errs.AppError)
+	SubmitForReview(string, string, string) (*Registration, bool, *errs.AppError)
+	FindPendingReview() ([]Registration, *errs.AppError)
+	Approve(string, string, string, string) (*Registration, *errs.AppError)
+	Reject(string, string, string, string) (*Registration, *errs.AppError)
+	Cleanup()
 }
 
 type RegistrationRepositoryDb struct { //DB (adapter)
@@ -31,11 +39,17 @@ func NewRegistrationRepositoryDb(dbClient *sqlx.DB) RegistrationRepositoryDb {
 
 // IsEmailUsed queries the db if there is a Customer who already has the given email or a Registration made using this
 // email (and whether it has already been confirmed). This is to prevent multiple registrations from using the same email.
+// Rejected registrations are not counted, so that rejected applicants can register again.
 func (d RegistrationRepositoryDb) IsEmailUsed(email string) *errs.AppError {
+	return isEmailUsed(d.client, email)
+}
+
+// isEmailUsed is shared with other repos so that the check can be part of their transactions.
+func isEmailUsed(q sqlx.Queryer, email string) *errs.AppError {
 	var isExists bool
 
 	findCustomersSql := "SELECT EXISTS(SELECT 1 FROM customers WHERE email = ?)"
-	if err := d.client.Get(&isExists, findCustomersSql, email); err != nil {
+	if err := sqlx.Get(q, &isExists, findCustomersSql, email); err != nil {
 		logger.Error("Error while checking if customer with given email already exists: " + err.Error())
 		return errs.NewUnexpectedError("Unexpected database error")
 	}
@@ -44,8 +58,8 @@ func (d RegistrationRepositoryDb) IsEmailUsed(email string) *errs.AppError {
 		return errs.NewConflictError("Either the email has been used to register before or the username is already taken")
 	}
 
-	findRegistrationsSql := "SELECT EXISTS(SELECT 1 FROM registrations WHERE email = ?)"
-	if err := d.client.Get(&isExists, findRegistrationsSql, email); err != nil {
+	findRegistrationsSql := "SELECT EXISTS(SELECT 1 FROM registrations WHERE email = ? AND (status IS NULL OR status <> ?))"
+	if err := sqlx.Get(q, &isExists, findRegistrationsSql, email, RegistrationStatusRejected); err != nil {
 		logger.Error("Error while checking if registration with given email already exists: " + err.Error())
 		return errs.NewUnexpectedError("Unexpected database error")
 	}
@@ -58,15 +72,21 @@ func (d RegistrationRepositoryDb) IsEmailUsed(email string) *errs.AppError {
 }
 
 // IsUsernameTaken queries the db for a User who already has the given username or a Registration already made using
-// this username. This is to prevent multiple clients from taking the same username during sign-up.
+// this username. This is to prevent multiple clients from taking the same username during sign-up. As with
+// IsEmailUsed, rejected registrations are not counted.
 func (d RegistrationRepositoryDb) IsUsernameTaken(un string) *errs.AppError {
+	return isUsernameTaken(d.client, un)
+}
+
+// isUsernameTaken is shared with other repos so that the check can be part of their transactions.
+func isUsernameTaken(q sqlx.Queryer, un string) *errs.AppError {
 	var isExists bool
 	findSql := `SELECT EXISTS(
 		(SELECT 1 FROM users WHERE username = ?) 
 		UNION 
-		(SELECT 1 FROM registrations WHERE username = ?)
+		(SELECT 1 FROM registrations WHERE username = ? AND (status IS NULL OR status <> ?))
 	)`
-	if err := d.client.Get(&isExists, findSql, un, un); err != nil {
+	if err := sqlx.Get(q, &isExists, findSql, un, un, RegistrationStatusRejected); err != nil {
 		logger.Error("Error while checking if username is taken: " + err.Error())
 		return errs.NewUnexpectedError("Unexpected database error")
 	}
@@ -79,36 +99,171 @@ func (d RegistrationRepositoryDb) IsUsernameTaken(un string) *errs.AppError {
 	return nil //can proceed
 }
 
-// Save stores the given Registration in the db.
-func (d RegistrationRepositoryDb) Save(reg Registration) *errs.AppError {
-	_, err := d.client.Exec(`INSERT INTO registrations 
-    (email, name, date_of_birth, country, zipcode, username, password, role, created_on) VALUES (?,?,?,?,?,?,?,?,?)`,
-		reg.Email, reg.Name, reg.DateOfBirth, reg.Country, reg.Zipcode, reg.Username, reg.HashedPassword, reg.Role, reg.DateRegistered)
+// IsOnboardingOptionValid checks that there are active onboarding products for the given onboarding option, so that
+// registrations cannot choose an option that does not exist.
+func (d RegistrationRepositoryDb) IsOnboardingOptionValid(option string) *errs.AppError {
+	var isExists bool
+	findSql := "SELECT EXISTS(SELECT 1 FROM onboarding_products WHERE onboarding_option = ? AND is_active = 1)"
+	if err := d.client.Get(&isExists, findSql, option); err != nil {
+		logger.Error("Error while checking if onboarding option exists: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if !isExists {
+		logger.Error("Onboarding option does not exist")
+		return errs.NewValidationError("Please check that the Onboarding Option selected is correct.")
+	}
+
+	return nil
+}
+
+// Save stores the given Registration in the db along with the one-time token in the given claims and the given
+// OutboxEmail delivering the confirmation link containing it, in a single db transaction. The email is therefore only
+// sent if the Registration was saved. Rejected registrations using the same email or username are replaced.
+func (d RegistrationRepositoryDb) Save(reg Registration, claims *OneTimeTokenClaims, email OutboxEmail) *errs.AppError {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for saving registration: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if appErr := deleteRejected(tx, reg.Email, reg.Username); appErr != nil {
+		rollbackTx(tx, "saving of registration")
+		return appErr
+	}
+
+	_, err = tx.Exec(`INSERT INTO registrations 
+    (email, name, date_of_birth, country, country_code, zipcode, locale, onboarding_option, username, password, role, created_on) 
+    VALUES (?,?,?,?,?,?,?,?,?,?,?,?)`,
+		reg.Email, reg.Name, reg.DateOfBirth, reg.Country, reg.CountryCode, reg.Zipcode, reg.Locale, reg.OnboardingOption,
+		reg.Username, reg.HashedPassword, reg.Role, reg.DateRegistered)
 	if err != nil {
 		logger.Error("Error while saving registration: " + err.Error())
+		rollbackTx(tx, "saving of registration")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if appErr := enqueueConfirmationLink(tx, reg, claims, email); appErr != nil {
+		rollbackTx(tx, "saving of registration")
+		return appErr
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for saving registration: " + err.Error())
 		return errs.NewUnexpectedError("Unexpected database error")
 	}
+
 	return nil
 }
 
-// UpdateLastEmailedInfo increments the number of times a confirmation link has been sent to the email in the given
-// Registration, and updates the last send time using the given time string. This indicates a new link has been sent.
-func (d RegistrationRepositoryDb) UpdateLastEmailedInfo(reg Registration, timeStr string) *errs.AppError {
-	var numEmailAttempts int
-	getSql := "SELECT email_attempts FROM registrations WHERE email = ?"
-	if err := d.client.Get(&numEmailAttempts, getSql, reg.Email); err != nil {
-		logger.Error("Error while getting previous number of email attempts: " + err.Error())
+// EnqueueConfirmationLink records the one-time token in the given claims for the given Registration and adds the given
+// OutboxEmail delivering the new confirmation link containing it to the outbox, in a single db transaction.
+func (d RegistrationRepositoryDb) EnqueueConfirmationLink(reg Registration, claims *OneTimeTokenClaims, email OutboxEmail) *errs.AppError {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for enqueueing confirmation link: " + err.Error())
 		return errs.NewUnexpectedError("Unexpected database error")
 	}
 
-	updateSql := "UPDATE registrations SET email_attempts = ?, last_emailed_on = ? WHERE email = ?"
-	if _, err := d.client.Exec(updateSql, numEmailAttempts+1, timeStr, reg.Email); err != nil {
+	if appErr := enqueueConfirmationLink(tx, reg, claims, email); appErr != nil {
+		rollbackTx(tx, "enqueueing of confirmation link")
+		return appErr
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for enqueueing confirmation link: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// ForceEnqueueConfirmationLink is like EnqueueConfirmationLink, but for a link resent by the given admin regardless of
+// the limits on resending. It is not counted as an attempt at emailing the Registration, since the admin and the given
+// reason are recorded instead in the same db transaction for auditing.
+func (d RegistrationRepositoryDb) ForceEnqueueConfirmationLink(reg Registration, claims *OneTimeTokenClaims, email OutboxEmail, admin string, reason string) *errs.AppError {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for force resending confirmation link: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if appErr := saveConfirmationLink(tx, claims, email); appErr != nil {
+		rollbackTx(tx, "force resending of confirmation link")
+		return appErr
+	}
+
+	insertSql := "INSERT INTO forced_resends (email, resent_by, reason, resent_on) VALUES (?, ?, ?, ?)"
+	if _, err = tx.Exec(insertSql, reg.Email, admin, reason, email.DateCreated); err != nil {
+		logger.Error("Error while recording forced resend of confirmation link: " + err.Error())
+		rollbackTx(tx, "force resending of confirmation link")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for force resending confirmation link: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return nil
+}
+
+// FindForcedResends retrieves the records of the confirmation links force resent by admins to the given email, the
+// latest first.
+func (d RegistrationRepositoryDb) FindForcedResends(email string) ([]ForcedResend, *errs.AppError) {
+	resends := make([]ForcedResend, 0)
+	findSql := "SELECT * FROM forced_resends WHERE email = ? ORDER BY resend_id DESC"
+	if err := d.client.Select(&resends, findSql, email); err != nil {
+		logger.Error("Error while retrieving forced resends: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return resends, nil
+}
+
+// enqueueConfirmationLink saves the confirmation link using saveConfirmationLink and counts it as a new attempt at
+// emailing the given Registration. If the window of ResendEmailAttemptsWindow for counting attempts has passed, a new
+// window is started with the count reset to 1. This is done in a single statement so that concurrent sends are all
+// counted.
+func enqueueConfirmationLink(tx *sqlx.Tx, reg Registration, claims *OneTimeTokenClaims, email OutboxEmail) *errs.AppError {
+	if appErr := saveConfirmationLink(tx, claims, email); appErr != nil {
+		return appErr
+	}
+
+	timeEmailed, err := time.Parse(FormatDateTime, email.DateCreated)
+	if err != nil {
+		logger.Error("Error while parsing time last emailed: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected server-side error")
+	}
+	windowCutoff := timeEmailed.Add(-ResendEmailAttemptsWindow).Format(FormatDateTime)
+
+	updateSql := `UPDATE registrations SET 
+		email_attempts = IF(email_window_start IS NULL OR email_window_start <= ?, 1, email_attempts + 1), 
+		email_window_start = IF(email_window_start IS NULL OR email_window_start <= ?, ?, email_window_start), 
+		last_emailed_on = ? 
+		WHERE email = ?`
+	if _, err = tx.Exec(updateSql, windowCutoff, windowCutoff, email.DateCreated, email.DateCreated, reg.Email); err != nil {
 		logger.Error("Error while updating last emailed information for a registration: " + err.Error())
 		return errs.NewUnexpectedError("Unexpected database error")
 	}
 	return nil
 }
 
+// saveConfirmationLink saves the one-time token in the given claims, which invalidates all links previously sent to
+// the email, and adds the given OutboxEmail delivering the link to the outbox.
+func saveConfirmationLink(tx *sqlx.Tx, claims *OneTimeTokenClaims, email OutboxEmail) *errs.AppError {
+	if err := saveOneTimeToken(tx, claims.ID, claims.Email, OneTimeTokenPurposeRegistration, claims.ExpiresAt.Time); err != nil {
+		logger.Error("Error while saving one-time token: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if err := enqueueEmail(tx, email.ForToken(claims.ID)); err != nil {
+		logger.Error("Error while enqueueing confirmation email: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	return nil
+}
+
 // FindFromLoginDetails retrieves a Registration record using the given username and password. It may or may not exist
 // yet during login, so a nil Registration is returned instead of an error if it does not exist.
 func (d RegistrationRepositoryDb) FindFromLoginDetails(un string, pw string) (*Registration, *errs.AppError) {
@@ -146,32 +301,248 @@ func (d RegistrationRepositoryDb) FindFromEmail(email string) (*Registration, *e
 	return &registration, nil
 }
 
-// CreateNecessaryAccounts creates the necessary instances for a newly-registered user based on the given Registration:
-// a Customer, then, using the generated customer ID, a User and two different bank accounts (for demonstration
-// purposes). It returns the customer ID.
-func (d RegistrationRepositoryDb) CreateNecessaryAccounts(reg *Registration, createTime string) (string, *errs.AppError) {
-	tx, err := d.client.Begin()
+// Confirm completes the Registration made using the given email in a single db transaction: the registration row is
+// locked, the one-time token with the given ID is used up, the necessary accounts are created and the registration is
+// updated to confirmed status. If any step fails, everything is rolled back. If the email of the registration had
+// already been verified (e.g. the confirmation link was clicked twice), nothing is changed and true is returned.
+// The Registration is returned as it is after this.
+func (d RegistrationRepositoryDb) Confirm(email string, tokenId string, confirmTime string) (*Registration, bool, *errs.AppError) {
+	return d.verifyEmail(email, tokenId, confirmTime, false)
+}
+
+// SubmitForReview is like Confirm but instead of creating the necessary accounts, the Registration is updated to
+// pending review status, to be approved or rejected by an admin later.
+func (d RegistrationRepositoryDb) SubmitForReview(email string, tokenId string, submitTime string) (*Registration, bool, *errs.AppError) {
+	return d.verifyEmail(email, tokenId, submitTime, true)
+}
+
+func (d RegistrationRepositoryDb) verifyEmail(email string, tokenId string, verifyTime string, isReviewRequired bool) (*Registration, bool, *errs.AppError) {
+	tx, err := d.client.Beginx()
 	if err != nil {
-		logger.Error("Error while starting db transaction for creating accounts for new registration: " + err.Error())
-		return "", errs.NewUnexpectedError("Unexpected database error")
+		logger.Error("Error while starting db transaction for verifying email of registration: " + err.Error())
+		return nil, false, errs.NewUnexpectedError("Unexpected database error")
 	}
 
+	registration, appErr := lock(tx, email)
+	if appErr != nil {
+		return nil, false, appErr
+	}
+
+	if registration.IsEmailVerified() {
+		rollbackTx(tx, "locking of registration with already verified email")
+		logger.Info("Email of registration was already verified")
+		return registration, true, nil
+	}
+
+	if appErr = registration.CheckExpiry(); appErr != nil {
+		rollbackTx(tx, "locking of expired registration")
+		return nil, false, appErr
+	}
+
+	if appErr = markOneTimeTokenUsed(tx, tokenId); appErr != nil {
+		rollbackTx(tx, "using up of one-time token")
+		return nil, false, appErr
+	}
+
+	var verified *Registration
+	if isReviewRequired {
+		verified = registration.SubmitForReview(verifyTime)
+	} else {
+		customerId, appErr := createNecessaryAccounts(tx, registration, verifyTime)
+		if appErr != nil {
+			rollbackTx(tx, "creation of accounts for new registration")
+			return nil, false, appErr
+		}
+		verified = registration.Confirm(customerId, verifyTime)
+	}
+
+	if appErr = updateRegistration(tx, verified); appErr != nil {
+		rollbackTx(tx, "verification of email of registration")
+		return nil, false, appErr
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for verifying email of registration: " + err.Error())
+		return nil, false, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return verified, false, nil
+}
+
+// FindPendingReview retrieves all Registration records that are waiting to be approved or rejected by an admin,
+// the earliest verified first.
+func (d RegistrationRepositoryDb) FindPendingReview() ([]Registration, *errs.AppError) {
+	registrations := make([]Registration, 0)
+	findSql := "SELECT * FROM registrations WHERE status = ? ORDER BY email_verified_on"
+	if err := d.client.Select(&registrations, findSql, RegistrationStatusPendingReview); err != nil {
+		logger.Error("Error while retrieving registrations pending review: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return registrations, nil
+}
+
+// Approve confirms the Registration made using the given email which must be pending review (and have its KYC documents
+// verified if required), in a single db transaction like Confirm. The given reviewer and reason are recorded, and the
+// email informing the applicant is added to the outbox. The approved Registration is returned.
+func (d RegistrationRepositoryDb) Approve(email string, reviewer string, reason string, reviewTime string) (*Registration, *errs.AppError) {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for approving registration: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	registration, appErr := lockPendingReview(tx, email)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if IsKycRequired() && !registration.IsKycVerified() {
+		rollbackTx(tx, "locking of registration with unverified KYC documents")
+		logger.Error("Cannot approve registration as its KYC documents are not verified")
+		return nil, errs.NewConflictError("KYC documents not verified yet")
+	}
+
+	customerId, appErr := createNecessaryAccounts(tx, registration, reviewTime)
+	if appErr != nil {
+		rollbackTx(tx, "creation of accounts for approved registration")
+		return nil, appErr
+	}
+
+	approved := registration.Confirm(customerId, reviewTime).Review(reviewer, reason, reviewTime)
+	if appErr = updateRegistration(tx, approved); appErr != nil {
+		rollbackTx(tx, "approval of registration")
+		return nil, appErr
+	}
+
+	if appErr = enqueueDecisionEmail(tx, approved, true); appErr != nil {
+		rollbackTx(tx, "approval of registration")
+		return nil, appErr
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for approving registration: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return approved, nil
+}
+
+// deleteRejected deletes, within the given transaction, the rejected registrations made using the given email or
+// username and their one-time tokens, so that a new Registration can take their place. Their KYC documents are left
+// to be removed as orphans.
+func deleteRejected(tx *sqlx.Tx, email string, username string) *errs.AppError {
+	deleteTokensSql := `DELETE FROM one_time_tokens WHERE email IN 
+		(SELECT email FROM registrations WHERE (email = ? OR username = ?) AND status = ?)`
+	if _, err := tx.Exec(deleteTokensSql, email, username, RegistrationStatusRejected); err != nil {
+		logger.Error("Error while removing one-time tokens of rejected registrations: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	deleteSql := "DELETE FROM registrations WHERE (email = ? OR username = ?) AND status = ?"
+	if _, err := tx.Exec(deleteSql, email, username, RegistrationStatusRejected); err != nil {
+		logger.Error("Error while removing rejected registrations: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	return nil
+}
+
+// Reject updates the Registration made using the given email which must be pending review to rejected status,
+// recording the given reviewer and reason and adding the email informing the applicant to the outbox in the same db
+// transaction. The rejected Registration is returned.
+func (d RegistrationRepositoryDb) Reject(email string, reviewer string, reason string, reviewTime string) (*Registration, *errs.AppError) {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for rejecting registration: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	registration, appErr := lockPendingReview(tx, email)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	rejected := registration.Reject().Review(reviewer, reason, reviewTime)
+	if appErr = updateRegistration(tx, rejected); appErr != nil {
+		rollbackTx(tx, "rejection of registration")
+		return nil, appErr
+	}
+
+	if appErr = enqueueDecisionEmail(tx, rejected, false); appErr != nil {
+		rollbackTx(tx, "rejection of registration")
+		return nil, appErr
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for rejecting registration: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return rejected, nil
+}
+
+// enqueueDecisionEmail adds the email informing the applicant of the given reviewed Registration whether it was
+// approved to the outbox, within the given transaction.
+func enqueueDecisionEmail(tx *sqlx.Tx, reg *Registration, isApproved bool) *errs.AppError {
+	email, appErr := NewRegistrationDecisionEmail(reg.Email, reg.Locale, isApproved, reg.ReviewReason.String)
+	if appErr != nil {
+		return appErr
+	}
+
+	if err := enqueueEmail(tx, *email); err != nil {
+		logger.Error("Error while enqueueing registration decision email: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	return nil
+}
+
+// lock retrieves the Registration made using the given email, locking its row until the given transaction ends.
+// The transaction is rolled back if this fails.
+func lock(tx *sqlx.Tx, email string) (*Registration, *errs.AppError) {
+	var registration Registration
+	if err := tx.Get(&registration, "SELECT * FROM registrations WHERE email = ? FOR UPDATE", email); err != nil {
+		rollbackTx(tx, "locking of registration")
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("The given registration does not exist")
+			return nil, errs.NewNotFoundError("Registration not found")
+		}
+		logger.Error("Error while locking registration: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return &registration, nil
+}
+
+// lockPendingReview is like lock but additionally ensures that the Registration is pending review.
+func lockPendingReview(tx *sqlx.Tx, email string) (*Registration, *errs.AppError) {
+	registration, appErr := lock(tx, email)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if !registration.IsPendingReview() {
+		rollbackTx(tx, "locking of registration not pending review")
+		logger.Error("Registration is not pending review")
+		return nil, errs.NewConflictError("Registration is not pending review")
+	}
+
+	return registration, nil
+}
+
+// createNecessaryAccounts creates the necessary instances for a newly-registered user based on the given Registration
+// within the given transaction: a Customer, then, using the generated customer ID, a User and a bank account for each
+// of the onboarding products that apply to the registration. It returns the customer ID.
+func createNecessaryAccounts(tx *sqlx.Tx, reg *Registration, createTime string) (string, *errs.AppError) {
 	result, err := tx.Exec("INSERT INTO customers (name, date_of_birth, email, country, zipcode, status) VALUES (?, ?, ?, ?, ?, ?)",
 		reg.Name, reg.DateOfBirth, reg.Email, reg.Country, reg.Zipcode, reg.Status)
 	if err != nil {
 		logger.Error("Error while creating customer: " + err.Error())
-		if rollbackErr := tx.Rollback(); rollbackErr != nil {
-			logger.Error("Error while rolling back creation of new customer: " + rollbackErr.Error())
-		}
 		return "", errs.NewUnexpectedError("Unexpected database error")
 	}
 
 	newCustomerId, err := result.LastInsertId()
 	if err != nil {
 		logger.Error("Error while getting id of newly inserted customer: " + err.Error())
-		if rollbackErr := tx.Rollback(); rollbackErr != nil {
-			logger.Error("Error while rolling back creation of new customer after trying to get id: " + rollbackErr.Error())
-		}
 		return "", errs.NewUnexpectedError("Unexpected database error")
 	}
 
@@ -179,52 +550,44 @@ func (d RegistrationRepositoryDb) CreateNecessaryAccounts(reg *Registration, cre
 		reg.Username, reg.HashedPassword, reg.Role, newCustomerId, createTime)
 	if err != nil {
 		logger.Error("Error while creating user: " + err.Error())
-		if rollbackErr := tx.Rollback(); rollbackErr != nil {
-			logger.Error("Error while rolling back creation of new user: " + rollbackErr.Error())
-		}
 		return "", errs.NewUnexpectedError("Unexpected database error")
 	}
 
-	// For demonstration purposes
+	products, appErr := findOnboardingProducts(tx, reg)
+	if appErr != nil {
+		return "", appErr
+	}
+
 	insertAccountsSql := "INSERT INTO accounts (customer_id, opening_date, account_type, amount, status) VALUES (?, ?, ?, ?, ?)"
-	newAccounts := []struct {
-		Type   string
-		Amount float64
-		Status string
-	}{
-		{"saving", 30000, "1"},
-		{"checking", 6000, "1"},
-	}
-	for k, v := range newAccounts {
-		result, err = tx.Exec(insertAccountsSql, newCustomerId, createTime, v.Type, v.Amount, v.Status)
-		if err != nil {
+	for k, v := range products {
+		if _, err = tx.Exec(insertAccountsSql, newCustomerId, createTime, v.AccountType, v.Amount, v.Status); err != nil {
 			logger.Error(fmt.Sprintf("Error while creating new account %d: %s", k+1, err.Error()))
-			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				logger.Error(fmt.Sprintf("Error while rolling back creation of new account %d: %s", k+1, err.Error()))
-			}
 			return "", errs.NewUnexpectedError("Unexpected database error")
 		}
 	}
-
-	if err = tx.Commit(); err != nil {
-		logger.Error("Error while committing transaction for creating accounts for new registration: " + err.Error())
-		return "", errs.NewUnexpectedError("Unexpected database error")
-	}
+	logger.Info(fmt.Sprintf("Opened %d account(s) for new customer", len(products)))
 
 	id := strconv.FormatInt(newCustomerId, 10)
 	return id, nil
 }
 
-// Update uses the confirmed Registration to update its related records in the db.
-func (d RegistrationRepositoryDb) Update(reg *Registration) *errs.AppError {
-	_, err := d.client.Exec("UPDATE registrations SET customer_id = ?, status = ?, confirmed_on = ? WHERE email = ?",
-		reg.CustomerId.String, reg.Status, reg.DateConfirmed.String, reg.Email)
+// updateRegistration uses the given Registration to update its status and related records in the db within the given
+// transaction.
+func updateRegistration(tx *sqlx.Tx, reg *Registration) *errs.AppError {
+	updateSql := `UPDATE registrations SET customer_id = ?, status = ?, email_verified_on = ?, confirmed_on = ?, 
+		reviewed_by = ?, reviewed_on = ?, review_reason = ? WHERE email = ?`
+	_, err := tx.Exec(updateSql, reg.CustomerId, reg.Status, reg.DateEmailVerified, reg.DateConfirmed,
+		reg.ReviewedBy, reg.DateReviewed, reg.ReviewReason, reg.Email)
 	if err != nil {
-		logger.Error("Error while updating registration to confirmed status: " + err.Error())
+		logger.Error("Error while updating status of registration: " + err.Error())
 		return errs.NewUnexpectedError("Unexpected database error")
 	}
 
-	_, err = d.client.Exec("UPDATE customers SET status = ? WHERE customer_id = ?",
+	if !reg.CustomerId.Valid {
+		return nil
+	}
+
+	_, err = tx.Exec("UPDATE customers SET status = ? WHERE customer_id = ?",
 		reg.Status, reg.CustomerId.String)
 	if err != nil {
 		logger.Error("Error while updating customer record to confirmed status: " + err.Error())
@@ -232,3 +595,60 @@ func (d RegistrationRepositoryDb) Update(reg *Registration) *errs.AppError {
 	}
 	return nil
 }
+
+// Cleanup removes Registration records left unconfirmed for longer than the expiry given by GetRegistrationExpiry,
+// together with the one-time tokens sent for them, every RegistrationCleanupInterval, indefinitely. This releases
+// their username and email to be used in new registrations.
+func (d RegistrationRepositoryDb) Cleanup() {
+	for {
+		time.Sleep(RegistrationCleanupInterval)
+
+		cutoff := time.Now().UTC().Add(-GetRegistrationExpiry()).Format(FormatDateTime)
+		numDeleted, appErr := d.deleteExpired(cutoff)
+		if appErr != nil {
+			continue
+		}
+		if numDeleted > 0 {
+			logger.Info(fmt.Sprintf("Removed %d expired registration(s)", numDeleted))
+		}
+	}
+}
+
+// deleteExpired deletes, in a single db transaction, the unconfirmed registrations made on or before the given cutoff
+// time and their one-time tokens. It returns the number of registrations deleted.
+func (d RegistrationRepositoryDb) deleteExpired(cutoff string) (int64, *errs.AppError) {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for removing expired registrations: " + err.Error())
+		return 0, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	deleteTokensSql := `DELETE FROM one_time_tokens WHERE email IN 
+		(SELECT email FROM registrations WHERE confirmed_on IS NULL AND email_verified_on IS NULL AND created_on <= ?)`
+	if _, err = tx.Exec(deleteTokensSql, cutoff); err != nil {
+		logger.Error("Error while removing one-time tokens of expired registrations: " + err.Error())
+		rollbackTx(tx, "removal of one-time tokens of expired registrations")
+		return 0, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	result, err := tx.Exec("DELETE FROM registrations WHERE confirmed_on IS NULL AND email_verified_on IS NULL AND created_on <= ?", cutoff)
+	if err != nil {
+		logger.Error("Error while removing expired registrations: " + err.Error())
+		rollbackTx(tx, "removal of expired registrations")
+		return 0, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	numDeleted, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Error while checking number of expired registrations removed: " + err.Error())
+		rollbackTx(tx, "removal of expired registrations")
+		return 0, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for removing expired registrations: " + err.Error())
+		return 0, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return numDeleted, nil
+}
@@ -5,6 +5,19 @@ import "github.com/aliciatay-zls/banking-lib/logger"
 const RoleAdmin = "admin"
 const RoleUser = "user"
 
+// RolesWithoutCustomer are the roles of users who are not customers, so have no customer ID. Only these roles can be
+// given to someone through an Invitation.
+var RolesWithoutCustomer = []string{RoleAdmin}
+
+func IsRoleWithoutCustomer(role string) bool {
+	for _, r := range RolesWithoutCustomer {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
 type RolePermissions struct {
 	rolePermissionsMap map[string][]string
 }
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"database/sql"
+	"github.com/aliciatay-zls/banking-auth/dto"
+)
+
+// Session is where and when a refresh token in the store was issued. Both are unknown for sessions started before
+// they were recorded.
+type Session struct { //business/domain object
+	ClientIp    sql.NullString `db:"client_ip"`
+	DateCreated sql.NullString `db:"created_on"`
+}
+
+func (s Session) ToExportDTO() dto.SessionExport {
+	return dto.SessionExport{
+		ClientIp:    s.ClientIp.String,
+		DateStarted: s.DateCreated.String,
+	}
+}
@@ -0,0 +1,171 @@
+package domain
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net"
+	"net/smtp"
+	"os"
+)
+
+const SmtpSecurityNone = "none"
+const SmtpSecurityStartTls = "starttls"
+const SmtpSecurityTls = "tls" //implicit TLS, e.g. on port 465
+
+const SmtpAuthNone = "none"
+const SmtpAuthPlain = "plain"
+const SmtpAuthLogin = "login"
+const SmtpAuthCramMd5 = "cram-md5"
+
+// SmtpEmailTransport sends messages through a remote SMTP server, optionally over TLS and authenticating with one of
+// the supported mechanisms.
+type SmtpEmailTransport struct { //adapter
+	host     string
+	port     string
+	security string
+	auth     string
+	user     string
+	password string
+}
+
+// NewSmtpEmailTransport creates an SmtpEmailTransport using the MAIL_SERVER_* environment variables. Unless set in
+// MAIL_SMTP_SECURITY and MAIL_SMTP_AUTH, STARTTLS and PLAIN authentication are used in production mode, while neither
+// is used otherwise (e.g. with MailHog).
+func NewSmtpEmailTransport() SmtpEmailTransport {
+	security, auth := SmtpSecurityNone, SmtpAuthNone
+	if os.Getenv("APP_ENV") == "production" {
+		security, auth = SmtpSecurityStartTls, SmtpAuthPlain
+	}
+	if val := os.Getenv("MAIL_SMTP_SECURITY"); val != "" {
+		security = val
+	}
+	if val := os.Getenv("MAIL_SMTP_AUTH"); val != "" {
+		auth = val
+	}
+
+	return SmtpEmailTransport{
+		host:     os.Getenv("MAIL_SERVER_ADDRESS"),
+		port:     os.Getenv("MAIL_SERVER_PORT"),
+		security: security,
+		auth:     auth,
+		user:     os.Getenv("MAIL_SERVER_USER"),
+		password: os.Getenv("MAIL_SERVER_PASSWORD"),
+	}
+}
+
+// Send opens a new connection with the remote SMTP server, initiates use of TLS and authenticates itself to the
+// server as configured, registers the sender and recipient, then sends the given message. On success, the result only
+// names the server, as net/smtp does not expose its final reply.
+func (t SmtpEmailTransport) Send(from string, rcptAddr string, msg []byte) (string, *errs.AppError) {
+	client, err := t.dial()
+	if err != nil {
+		logger.Error("Error while connecting to SMTP server: " + err.Error())
+		return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+	}
+	defer client.Close()
+
+	if t.security == SmtpSecurityStartTls {
+		logger.Info("Initiating TLS session with remote SMTP server...")
+		if err = client.StartTLS(&tls.Config{ServerName: t.host}); err != nil {
+			logger.Error("Error initiating TLS session: " + err.Error())
+			return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+		}
+	}
+
+	if auth := t.getAuth(); auth != nil {
+		logger.Info("Authenticating with remote SMTP server...")
+		if err = client.Auth(auth); err != nil {
+			logger.Error("Error authenticating with mail server: " + err.Error())
+			return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+		}
+	}
+
+	if err = client.Mail(from); err != nil {
+		logger.Error("Error while setting the sender: " + err.Error())
+		return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+	}
+	if err = client.Rcpt(rcptAddr); err != nil {
+		logger.Error(fmt.Sprintf("Error setting the recipient %s: %s", rcptAddr, err.Error()))
+		return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+	}
+
+	wc, err := client.Data()
+	if err != nil {
+		logger.Error("Error getting writer: " + err.Error())
+		return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+	}
+	if _, err = wc.Write(msg); err != nil {
+		logger.Error("Error sending email body: " + err.Error())
+		return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+	}
+	if err = wc.Close(); err != nil {
+		logger.Error("Error closing writer: " + err.Error())
+		return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+	}
+
+	if err = client.Quit(); err != nil {
+		logger.Error("Error while closing connection to SMTP server: " + err.Error())
+		return err.Error(), errs.NewUnexpectedError("Unexpected error sending email")
+	}
+
+	return "accepted by " + t.host, nil
+}
+
+// dial connects to the SMTP server, over TLS from the start if implicit TLS is configured.
+func (t SmtpEmailTransport) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(t.host, t.port)
+	if t.security != SmtpSecurityTls {
+		return smtp.Dial(addr)
+	}
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: t.host})
+	if err != nil {
+		return nil, err
+	}
+	return smtp.NewClient(conn, t.host)
+}
+
+// getAuth returns the smtp.Auth for the configured mechanism, or nil if no authentication is needed.
+func (t SmtpEmailTransport) getAuth() smtp.Auth {
+	switch t.auth {
+	case SmtpAuthPlain:
+		return smtp.PlainAuth("", t.user, t.password, t.host)
+	case SmtpAuthLogin:
+		return loginAuth{t.user, t.password}
+	case SmtpAuthCramMd5:
+		return smtp.CRAMMD5Auth(t.user, t.password)
+	default:
+		return nil
+	}
+}
+
+// loginAuth implements the LOGIN authentication mechanism, which net/smtp does not provide but some servers (e.g.
+// Microsoft 365) require. Like smtp.PlainAuth, it refuses to send credentials over an unencrypted connection.
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch string(fromServer) {
+	case "Username:":
+		return []byte(a.username), nil
+	case "Password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+	}
+}
@@ -172,6 +172,24 @@ func (r DefaultTokenRepository) GetClaimsFromToken(tokenStr string, claimsType s
 		if deserializeErr == nil {
 			return &claims, nil
 		}
+	} else if claimsType == TokenTypeEmailChange {
+		claims := EmailChangeTokenClaims{}
+		deserializeErr = nested.Claims(&publicKey, &claims)
+		if deserializeErr == nil {
+			return &claims, nil
+		}
+	} else if claimsType == TokenTypeDataExport {
+		claims := DataExportTokenClaims{}
+		deserializeErr = nested.Claims(&publicKey, &claims)
+		if deserializeErr == nil {
+			return &claims, nil
+		}
+	} else if claimsType == TokenTypeInvitation {
+		claims := InvitationTokenClaims{}
+		deserializeErr = nested.Claims(&publicKey, &claims)
+		if deserializeErr == nil {
+			return &claims, nil
+		}
 	} else {
 		logger.Error("Unknown claims type")
 		return nil, errs.NewUnexpectedError("Unexpected authorization error")
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net/url"
+	"time"
+)
+
+// TOTP (RFC 6238) parameters, which are the defaults supported by common authenticator apps.
+const TotpDigits = 6
+const TotpPeriod = time.Second * 30
+const TotpAllowedSkew = 1 //number of periods before and after the current one in which codes are still accepted
+const TotpIssuer = "Banking App"
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTotpSecret returns a random 160-bit TOTP secret in base32 form, to be entered into an authenticator app.
+func GenerateTotpSecret() (string, *errs.AppError) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		logger.Error("Error while generating TOTP secret: " + err.Error())
+		return "", errs.NewUnexpectedError("Unexpected server-side error")
+	}
+
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// BuildTotpUri forms the otpauth URI for the given TOTP secret of the given account, which authenticator apps can
+// scan as a QR code.
+func BuildTotpUri(account string, secret string) string {
+	u := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   TotpIssuer + ":" + account,
+	}
+
+	v := url.Values{}
+	v.Add("secret", secret)
+	v.Add("issuer", TotpIssuer)
+	v.Add("digits", fmt.Sprint(TotpDigits))
+	v.Add("period", fmt.Sprint(int(TotpPeriod.Seconds())))
+	u.RawQuery = v.Encode()
+
+	return u.String()
+}
+
+// VerifyTotpCode checks the given code against the given TOTP secret at the given time, allowing for TotpAllowedSkew
+// periods of clock drift. It returns the time step the code is for, so that each code can only be used once.
+func VerifyTotpCode(secret string, code string, t time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		logger.Error("Error while decoding TOTP secret: " + err.Error())
+		return 0, false
+	}
+
+	currentStep := t.Unix() / int64(TotpPeriod.Seconds())
+	for step := currentStep - TotpAllowedSkew; step <= currentStep+TotpAllowedSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(generateHotpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// generateHotpCode computes the HOTP (RFC 4226) code of the given key for the given counter.
+func generateHotpCode(key []byte, counter int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < TotpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TotpDigits, value%mod)
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"database/sql"
+	"github.com/aliciatay-zls/banking-lib/errs"
+)
+
+// UsernameReminder tracks the emails sent to remind a client of their username, so that they can be limited in the
+// same way as resending confirmation links. It is kept for every email that reminders were requested for, whether it
+// is registered or not, so that the limits do not reveal which emails are registered.
+type UsernameReminder struct { //business/domain object
+	Email            string
+	EmailAttempts    int            `db:"email_attempts"`     //within the window starting from EmailWindowStart
+	EmailWindowStart sql.NullString `db:"email_window_start"` //reset once ResendEmailAttemptsWindow has passed
+	DateLastEmailed  sql.NullString `db:"last_emailed_on"`
+}
+
+// CanSend checks that another reminder can be sent, which is always the case for the first one.
+func (u UsernameReminder) CanSend() *errs.AppError {
+	if !u.DateLastEmailed.Valid {
+		return nil
+	}
+	_, appErr := checkEmailAttempts(u.EmailAttempts, u.EmailWindowStart, u.DateLastEmailed.String)
+	return appErr
+}
@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type UsernameReminderRepository interface { //repo (secondary port)
+	Find(string) (*UsernameReminder, *errs.AppError)
+	UpdateLastEmailedInfo(string, string, *OutboxEmail) *errs.AppError
+	FindUsername(string) (string, string, *errs.AppError)
+}
+
+type UsernameReminderRepositoryDb struct { //DB (adapter)
+	client *sqlx.DB
+}
+
+func NewUsernameReminderRepositoryDb(dbClient *sqlx.DB) UsernameReminderRepositoryDb {
+	return UsernameReminderRepositoryDb{dbClient}
+}
+
+// Find retrieves the UsernameReminder for the given email. If no reminder was requested for the email before, a new
+// UsernameReminder is returned instead of an error.
+func (d UsernameReminderRepositoryDb) Find(email string) (*UsernameReminder, *errs.AppError) {
+	reminder := UsernameReminder{Email: email}
+	findSql := "SELECT email, email_attempts, email_window_start, last_emailed_on FROM username_reminders WHERE email = ?"
+	if err := d.client.Get(&reminder, findSql, email); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Error("Error while retrieving username reminder: " + err.Error())
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return &reminder, nil
+}
+
+// UpdateLastEmailedInfo records a reminder requested for the given email at the given time, counting it within the
+// current window of attempts as in RegistrationRepositoryDb.EnqueueConfirmationLink. The given OutboxEmail with the
+// username, if any, is added to the outbox in the same db transaction.
+func (d UsernameReminderRepositoryDb) UpdateLastEmailedInfo(email string, timeStr string, reminderEmail *OutboxEmail) *errs.AppError {
+	timeEmailed, err := time.Parse(FormatDateTime, timeStr)
+	if err != nil {
+		logger.Error("Error while parsing time last emailed: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected server-side error")
+	}
+	windowCutoff := timeEmailed.Add(-ResendEmailAttemptsWindow).Format(FormatDateTime)
+
+	upsertSql := `INSERT INTO username_reminders (email, email_attempts, email_window_start, last_emailed_on) 
+		VALUES (?, 1, ?, ?) 
+		ON DUPLICATE KEY UPDATE 
+		email_attempts = IF(email_window_start IS NULL OR email_window_start <= ?, 1, email_attempts + 1), 
+		email_window_start = IF(email_window_start IS NULL OR email_window_start <= ?, ?, email_window_start), 
+		last_emailed_on = ?`
+
+	tx, err := d.client.Beginx()
+	if err != nil {
+		logger.Error("Error while starting db transaction for username reminder: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if _, err = tx.Exec(upsertSql, email, timeStr, timeStr, windowCutoff, windowCutoff, timeStr, timeStr); err != nil {
+		logger.Error("Error while updating last emailed information for a username reminder: " + err.Error())
+		rollbackTx(tx, "username reminder")
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if reminderEmail != nil {
+		if err = enqueueEmail(tx, *reminderEmail); err != nil {
+			logger.Error("Error while enqueueing username reminder email: " + err.Error())
+			rollbackTx(tx, "username reminder")
+			return errs.NewUnexpectedError("Unexpected database error")
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error while committing transaction for username reminder: " + err.Error())
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	return nil
+}
+
+// FindUsername retrieves the username and locale of the user who is the customer with the given email. An empty
+// username is returned instead of an error if there is no such user.
+func (d UsernameReminderRepositoryDb) FindUsername(email string) (string, string, *errs.AppError) {
+	var user struct {
+		Username string
+		Locale   string
+	}
+	findSql := `SELECT u.username, COALESCE(r.locale, ?) AS locale FROM users u 
+		JOIN customers c ON c.customer_id = u.customer_id 
+		LEFT JOIN registrations r ON r.customer_id = c.customer_id 
+		WHERE c.email = ?`
+	if err := d.client.Get(&user, findSql, DefaultLocale, email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", nil
+		}
+		logger.Error("Error while retrieving username from email: " + err.Error())
+		return "", "", errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	return user.Username, user.Locale, nil
+}
@@ -1,23 +1,57 @@
 package domain
 
 import (
-	"golang.org/x/time/rate"
+	"github.com/aliciatay-zls/banking-auth/dto"
 	"time"
 )
 
 const LastSeenBeforeCleanupInterval = 3 * time.Minute
 const CleanupInterval = time.Minute
 
+// Visitor holds the rate limiting state of a key, using the generic cell rate algorithm (GCRA): a request is allowed
+// if it does not arrive too early compared to its theoretical arrival time (TAT), which then moves forward by the
+// interval between requests of the rule. This behaves like a token bucket holding Limit tokens refilled one every
+// Period/Limit, but only a single timestamp needs to be stored, so the state can be shared through the db.
 type Visitor struct { //business/domain object
-	IPAddr   string
-	Limiter  *rate.Limiter
+	Key      string    //route, rule and value of the dimension limited, e.g. the IP address
+	Tat      time.Time //the bucket is full from then on
 	LastSeen time.Time
 }
 
-func NewVisitor(ip string) *Visitor {
+// NewVisitor creates the Visitor with the given key, whose bucket is full.
+func NewVisitor(key string, now time.Time) *Visitor {
 	return &Visitor{
-		IPAddr:   ip,
-		Limiter:  rate.NewLimiter(1, 1), //strictly 1 request per second
-		LastSeen: time.Now().UTC(),
+		Key:      key,
+		Tat:      now,
+		LastSeen: now,
 	}
 }
+
+// Allow checks whether the given rule allows a request by the Visitor at the given time and, if so, takes a token
+// from its bucket. It returns the state of the bucket afterwards.
+func (v *Visitor) Allow(rule RateLimitRule, now time.Time) dto.RateLimitStatus {
+	v.LastSeen = now
+	interval := rule.Period / time.Duration(rule.Limit)
+	tat := v.Tat
+	if tat.Before(now) {
+		tat = now
+	}
+
+	status := dto.RateLimitStatus{Limit: rule.Limit}
+	if next := tat.Add(interval); next.Sub(now) > rule.Period { //bucket is empty
+		status.Reset = tat.Sub(now)
+		status.RetryAfter = next.Add(-rule.Period).Sub(now)
+		return status
+	}
+	v.Tat = tat.Add(interval)
+	status.IsAllowed = true
+	status.Remaining = int((rule.Period - v.Tat.Sub(now)) / interval)
+	status.Reset = v.Tat.Sub(now)
+	return status
+}
+
+// IsOutdated checks whether the Visitor was last seen long enough ago and its bucket is full again, so forgetting it
+// does not reset its limit.
+func (v Visitor) IsOutdated(now time.Time) bool {
+	return now.Sub(v.LastSeen) > LastSeenBeforeCleanupInterval && !now.Before(v.Tat)
+}
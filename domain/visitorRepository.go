@@ -1,17 +1,55 @@
 package domain
 
 import (
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/jmoiron/sqlx"
+	"os"
 	"sync"
 	"time"
 )
 
 //Reference: https://www.alexedwards.net/blog/how-to-rate-limit-http-requests
 
+const VisitorStoreMemory = "memory" //kept by each instance of the server, and lost when it restarts
+const VisitorStoreDb = "db"         //shared by all instances of the server
+
 type VisitorRepository interface { //repo (secondary port)
-	GetVisitor(string) *Visitor
+	Allow([]RateLimitCheck) ([]dto.RateLimitStatus, *errs.AppError)
 	Cleanup()
 }
 
+// RateLimitCheck is a request to be counted by the given rule against the entry for the given key.
+type RateLimitCheck struct {
+	Key  string //route, rule and value of the dimension limited, e.g. the IP address
+	Rule RateLimitRule
+}
+
+// isAllAllowed checks whether every one of the given statuses allows the request.
+func isAllAllowed(statuses []dto.RateLimitStatus) bool {
+	for _, status := range statuses {
+		if !status.IsAllowed {
+			return false
+		}
+	}
+	return true
+}
+
+// NewVisitorRepository creates the VisitorRepository chosen by the RATE_LIMIT_STORE environment variable, which
+// defaults to memory.
+func NewVisitorRepository(dbClient *sqlx.DB) VisitorRepository {
+	switch store := os.Getenv("RATE_LIMIT_STORE"); store {
+	case "", VisitorStoreMemory:
+		return NewDefaultVisitorRepository()
+	case VisitorStoreDb:
+		return NewVisitorRepositoryDb(dbClient)
+	default:
+		logger.Fatal("Unknown rate limit store " + store)
+		return nil
+	}
+}
+
 type DefaultVisitorRepository struct { //adapter
 	mu          sync.Mutex
 	visitorsMap map[string]*Visitor
@@ -21,30 +59,43 @@ func NewDefaultVisitorRepository() *DefaultVisitorRepository {
 	return &DefaultVisitorRepository{visitorsMap: make(map[string]*Visitor)}
 }
 
-// GetVisitor retrieves the entry for the given IP address, creating an entry if it does not exist yet,
-// and updates/sets the time that it last visited the site to the current time.
-func (r *DefaultVisitorRepository) GetVisitor(ip string) *Visitor {
+// Allow checks whether each of the given checks allows a request by the entry for its key, creating the entries that
+// do not exist yet, and returns the status of each check in the same order. Tokens are only taken from the buckets if
+// every check allows the request, so a refused request does not use up the budgets of the other checks.
+func (r *DefaultVisitorRepository) Allow(checks []RateLimitCheck) ([]dto.RateLimitStatus, *errs.AppError) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	v, ok := r.visitorsMap[ip]
-	if !ok {
-		visitor := NewVisitor(ip)
-		r.visitorsMap[ip] = visitor
-		return visitor
+	now := time.Now().UTC()
+	statuses := make([]dto.RateLimitStatus, 0, len(checks))
+	updated := make([]Visitor, 0, len(checks))
+	for _, check := range checks {
+		v := NewVisitor(check.Key, now)
+		if existing, ok := r.visitorsMap[check.Key]; ok {
+			v = existing
+		}
+		next := *v
+		statuses = append(statuses, next.Allow(check.Rule, now))
+		updated = append(updated, next)
+	}
+
+	if isAllAllowed(statuses) {
+		for i := range updated {
+			r.visitorsMap[updated[i].Key] = &updated[i]
+		}
 	}
-	v.LastSeen = time.Now().UTC()
-	return v
+	return statuses, nil
 }
 
-// Cleanup removes outdated IP addresses (last visited the site more than 3 minutes ago) every 1 minute, indefinitely.
+// Cleanup removes outdated entries (last visited the site more than 3 minutes ago, and long enough ago for their limit
+// to be fully restored) every 1 minute, indefinitely.
 func (r *DefaultVisitorRepository) Cleanup() {
 	for {
 		time.Sleep(CleanupInterval)
 
 		r.mu.Lock()
 		for k, v := range r.visitorsMap {
-			if time.Now().UTC().Sub(v.LastSeen) > LastSeenBeforeCleanupInterval {
+			if v.IsOutdated(time.Now().UTC()) {
 				delete(r.visitorsMap, k)
 			}
 		}
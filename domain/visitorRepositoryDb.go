@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"sort"
+	"time"
+)
+
+const visitorDbMaxAttempts = 3
+const mySqlErrDuplicateKey = 1062
+const mySqlErrDeadlock = 1213
+
+type VisitorRepositoryDb struct { //DB (adapter)
+	client *sqlx.DB
+}
+
+func NewVisitorRepositoryDb(dbClient *sqlx.DB) VisitorRepositoryDb {
+	return VisitorRepositoryDb{dbClient}
+}
+
+// Allow checks whether each of the given checks allows a request by the entry for its key, creating the entries that
+// do not exist yet, and returns the status of each check in the same order. Tokens are only taken from the buckets if
+// every check allows the request. The entries are locked from being read until they are updated, so concurrent
+// requests to any instance of the server are counted one after the other. Attempts that lose a race for an entry (a
+// deadlock between locks, or both creating it) are retried.
+func (d VisitorRepositoryDb) Allow(checks []RateLimitCheck) ([]dto.RateLimitStatus, *errs.AppError) {
+	var err error
+	for i := 0; i < visitorDbMaxAttempts; i++ {
+		var statuses []dto.RateLimitStatus
+		if statuses, err = d.allow(checks); err == nil {
+			return statuses, nil
+		}
+		if !isRetryableMySqlError(err) {
+			break
+		}
+	}
+
+	logger.Error("Error while rate limiting visitor: " + err.Error())
+	return nil, errs.NewUnexpectedError("Unexpected database error")
+}
+
+// allow runs one attempt of Allow in a db transaction, locking the entries in order of their keys before they are
+// read, so that concurrent attempts do not deadlock, and inserting them only if they are missing.
+func (d VisitorRepositoryDb) allow(checks []RateLimitCheck) ([]dto.RateLimitStatus, error) {
+	tx, err := d.client.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]int, len(checks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return checks[order[a]].Key < checks[order[b]].Key })
+
+	now := time.Now().UTC()
+	statuses := make([]dto.RateLimitStatus, len(checks))
+	visitors := make([]Visitor, len(checks))
+	isNew := make([]bool, len(checks))
+	selectSql := `SELECT tat FROM rate_limit_visitors WHERE visitor_key = ? FOR UPDATE`
+	for _, i := range order {
+		var tat int64
+		if err = tx.Get(&tat, selectSql, checks[i].Key); errors.Is(err, sql.ErrNoRows) {
+			tat, isNew[i] = now.UnixMicro(), true
+		} else if err != nil {
+			rollbackTx(tx, "rate limiting visitor")
+			return nil, err
+		}
+
+		visitors[i] = Visitor{Key: checks[i].Key, Tat: time.UnixMicro(tat).UTC()}
+		statuses[i] = visitors[i].Allow(checks[i].Rule, now)
+	}
+
+	if isAllAllowed(statuses) {
+		for _, i := range order {
+			upsertSql := `UPDATE rate_limit_visitors SET tat = ? WHERE visitor_key = ?`
+			if isNew[i] {
+				upsertSql = `INSERT INTO rate_limit_visitors (tat, visitor_key) VALUES (?, ?)`
+			}
+			if _, err = tx.Exec(upsertSql, visitors[i].Tat.UnixMicro(), visitors[i].Key); err != nil {
+				rollbackTx(tx, "rate limiting visitor")
+				return nil, err
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// isRetryableMySqlError checks whether the given error is a deadlock or a duplicate key, which concurrent
+// transactions on the same entry can cause and which succeed when retried.
+func isRetryableMySqlError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == mySqlErrDeadlock || mysqlErr.Number == mySqlErrDuplicateKey)
+}
+
+// Cleanup removes the entries whose bucket has been full again for more than 3 minutes every 1 minute, indefinitely.
+func (d VisitorRepositoryDb) Cleanup() {
+	for {
+		time.Sleep(CleanupInterval)
+
+		cutoff := time.Now().UTC().Add(-LastSeenBeforeCleanupInterval).UnixMicro()
+		result, err := d.client.Exec(`DELETE FROM rate_limit_visitors WHERE tat < ?`, cutoff)
+		if err != nil {
+			logger.Error("Error while removing outdated rate limiting visitors: " + err.Error())
+			continue
+		}
+		if numDeleted, err := result.RowsAffected(); err == nil && numDeleted > 0 {
+			logger.Info(fmt.Sprintf("Removed %d outdated rate limiting visitor(s)", numDeleted))
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"testing"
+	"time"
+)
+
+var visitorTestStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestVisitorAllow(t *testing.T) {
+	second := time.Second
+	tests := []struct {
+		name     string
+		rule     RateLimitRule
+		requests []time.Duration //since visitorTestStart, the status of the last one is checked
+		want     dto.RateLimitStatus
+	}{
+		{
+			name:     "first request",
+			rule:     RateLimitRule{RateLimitKeyIp, 3, 3 * second},
+			requests: []time.Duration{0},
+			want:     dto.RateLimitStatus{IsAllowed: true, Limit: 3, Remaining: 2, Reset: second},
+		},
+		{
+			name:     "burst up to limit",
+			rule:     RateLimitRule{RateLimitKeyIp, 3, 3 * second},
+			requests: []time.Duration{0, 0, 0},
+			want:     dto.RateLimitStatus{IsAllowed: true, Limit: 3, Remaining: 0, Reset: 3 * second},
+		},
+		{
+			name:     "burst over limit",
+			rule:     RateLimitRule{RateLimitKeyIp, 3, 3 * second},
+			requests: []time.Duration{0, 0, 0, 0},
+			want:     dto.RateLimitStatus{Limit: 3, Reset: 3 * second, RetryAfter: second},
+		},
+		{
+			name:     "refused request does not take a token",
+			rule:     RateLimitRule{RateLimitKeyIp, 3, 3 * second},
+			requests: []time.Duration{0, 0, 0, 0, 0},
+			want:     dto.RateLimitStatus{Limit: 3, Reset: 3 * second, RetryAfter: second},
+		},
+		{
+			name:     "one token refilled after burst",
+			rule:     RateLimitRule{RateLimitKeyIp, 3, 3 * second},
+			requests: []time.Duration{0, 0, 0, second},
+			want:     dto.RateLimitStatus{IsAllowed: true, Limit: 3, Remaining: 0, Reset: 3 * second},
+		},
+		{
+			name:     "bucket full again after period",
+			rule:     RateLimitRule{RateLimitKeyIp, 3, 3 * second},
+			requests: []time.Duration{0, 0, 0, 10 * second},
+			want:     dto.RateLimitStatus{IsAllowed: true, Limit: 3, Remaining: 2, Reset: second},
+		},
+		{
+			name:     "limit of one",
+			rule:     RateLimitRule{RateLimitKeyIp, 1, second},
+			requests: []time.Duration{0, 500 * time.Millisecond},
+			want:     dto.RateLimitStatus{Limit: 1, Reset: 500 * time.Millisecond, RetryAfter: 500 * time.Millisecond},
+		},
+		{
+			name:     "partly refilled token not counted as remaining",
+			rule:     RateLimitRule{RateLimitKeyEmail, 5, time.Minute},
+			requests: []time.Duration{0, 6 * second},
+			want:     dto.RateLimitStatus{IsAllowed: true, Limit: 5, Remaining: 3, Reset: 18 * second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVisitor("key", visitorTestStart)
+			var got dto.RateLimitStatus
+			for _, offset := range tt.requests {
+				got = v.Allow(tt.rule, visitorTestStart.Add(offset))
+			}
+			if got != tt.want {
+				t.Errorf("Allow() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisitorIsOutdated(t *testing.T) {
+	tests := []struct {
+		name string
+		tat  time.Duration //since visitorTestStart, which is when it was last seen
+		now  time.Duration
+		want bool
+	}{
+		{"recently seen", 0, time.Minute, false},
+		{"seen long ago with full bucket", 0, LastSeenBeforeCleanupInterval + time.Second, true},
+		{"seen long ago with bucket still refilling", time.Hour, LastSeenBeforeCleanupInterval + time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := Visitor{Key: "key", Tat: visitorTestStart.Add(tt.tat), LastSeen: visitorTestStart}
+			if got := v.IsOutdated(visitorTestStart.Add(tt.now)); got != tt.want {
+				t.Errorf("IsOutdated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDefaultVisitorRepositoryAllow checks that a request refused by one rule does not use up the budget of the others.
+func TestDefaultVisitorRepositoryAllow(t *testing.T) {
+	repo := NewDefaultVisitorRepository()
+	ipCheck := RateLimitCheck{Key: "Login/0/ip/192.0.2.1", Rule: RateLimitRule{RateLimitKeyIp, 3, time.Hour}}
+	usernameCheck := RateLimitCheck{Key: "Login/1/username/a", Rule: RateLimitRule{RateLimitKeyUsername, 1, time.Hour}}
+
+	tests := []struct {
+		name   string
+		checks []RateLimitCheck
+		want   []bool
+	}{
+		{"both allow", []RateLimitCheck{ipCheck, usernameCheck}, []bool{true, true}},
+		{"username refuses", []RateLimitCheck{ipCheck, usernameCheck}, []bool{true, false}},
+		{"username refuses again", []RateLimitCheck{ipCheck, usernameCheck}, []bool{true, false}},
+		{"ip budget left for another username", []RateLimitCheck{ipCheck}, []bool{true}},
+		{"ip budget used up", []RateLimitCheck{ipCheck}, []bool{true}},
+		{"ip refuses", []RateLimitCheck{ipCheck}, []bool{false}},
+	}
+	for _, tt := range tests {
+		statuses, appErr := repo.Allow(tt.checks)
+		if appErr != nil {
+			t.Fatalf("%s: Allow() error = %s", tt.name, appErr.Message)
+		}
+		for i, status := range statuses {
+			if status.IsAllowed != tt.want[i] {
+				t.Errorf("%s: Allow()[%d].IsAllowed = %v, want %v", tt.name, i, status.IsAllowed, tt.want[i])
+			}
+		}
+	}
+}
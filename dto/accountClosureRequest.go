@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+type AccountClosureRequest struct {
+	Password string `json:"password" validate:"required,max=64,ascii"`
+	Reason   string `json:"reason" validate:"max=255"`
+}
+
+func (r AccountClosureRequest) Validate() *errs.AppError {
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("Account closure request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		if errsArr[0].Field() == "Reason" {
+			return errs.NewValidationError("Reason must be at most 255 characters long")
+		}
+		return errs.NewValidationError("Incorrect password")
+	}
+	return nil
+}
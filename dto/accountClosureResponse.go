@@ -0,0 +1,20 @@
+package dto
+
+type AccountClosureResponse struct {
+	ClosureId     string `json:"closure_id"`
+	CustomerId    string `json:"customer_id"`
+	Reason        string `json:"reason"`
+	Status        string `json:"status"`
+	DateRequested string `json:"requested_on"`
+	IsOnHold      bool   `json:"is_on_hold"`
+}
+
+type ErasureCertificateResponse struct {
+	ClosureId     string `json:"closure_id"`
+	CustomerId    string `json:"customer_id"`
+	DateRequested string `json:"requested_on"`
+	ErasedBy      string `json:"erased_by"`
+	DateErased    string `json:"erased_on"`
+	ErasedRecords string `json:"erased_records"`
+	Digest        string `json:"digest"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+// CustomerActionRequest is for an admin's action on the data of a customer, e.g. erasing it or placing a retention
+// hold on it.
+type CustomerActionRequest struct {
+	CustomerId string `json:"customer_id" validate:"required,max=10,numeric"`
+	Reason     string `json:"reason" validate:"max=255"`
+}
+
+// Validate checks the request, where a reason must be given if required for the action.
+func (r CustomerActionRequest) Validate(isReasonRequired bool) *errs.AppError {
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("Customer action request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		if errsArr[0].Field() == "Reason" {
+			return errs.NewValidationError("Reason must be at most 255 characters long")
+		}
+		return errs.NewValidationError("Invalid customer ID")
+	}
+
+	if isReasonRequired && r.Reason == "" {
+		logger.Error("No reason given for action on customer")
+		return errs.NewValidationError("Reason must be given")
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package dto
+
+type DataExportResponse struct {
+	ExportId      string `json:"export_id"`
+	Status        string `json:"status"`
+	DateRequested string `json:"requested_on"`
+}
+
+// DataExportArchive is the machine-readable archive of the data held by the auth server about a customer.
+type DataExportArchive struct {
+	DateGenerated  string                  `json:"generated_on"`
+	User           Identity                `json:"user"`
+	Profile        *ProfileResponse        `json:"profile"`
+	Registration   *RegistrationExport     `json:"registration"`
+	KycDocuments   []KycDocumentResponse   `json:"kyc_documents"`
+	ActiveSessions []SessionExport         `json:"active_sessions"`
+	EmailsSent     []OneTimeTokenExport    `json:"emails_sent"`
+	EmailLog       []EmailLogResponse      `json:"email_log"`
+	ForcedResends  []ForcedResendExport    `json:"forced_resends"`
+	ProfileChanges []ProfileChangeResponse `json:"profile_changes"`
+}
+
+type RegistrationExport struct {
+	Email             string `json:"email"`
+	Name              string `json:"name"`
+	DateOfBirth       string `json:"date_of_birth"`
+	Country           string `json:"country"`
+	Zipcode           string `json:"zipcode"`
+	Locale            string `json:"locale"`
+	Username          string `json:"username"`
+	OnboardingOption  string `json:"onboarding_option"`
+	KycStatus         string `json:"kyc_status"`
+	DateRegistered    string `json:"created_on"`
+	DateLastEmailed   string `json:"last_emailed_on"`
+	DateEmailVerified string `json:"email_verified_on"`
+	DateConfirmed     string `json:"confirmed_on"`
+	DateReviewed      string `json:"reviewed_on"`
+	ReviewReason      string `json:"review_reason"`
+}
+
+// OneTimeTokenExport describes an email containing a one-time link that was sent to the customer.
+type OneTimeTokenExport struct {
+	Email           string `json:"email"`
+	Purpose         string `json:"purpose"`
+	DateSent        string `json:"sent_on"`
+	DateExpires     string `json:"expires_on"`
+	DateUsed        string `json:"used_on"`
+	DateInvalidated string `json:"invalidated_on"`
+}
+
+// SessionExport describes a session of the customer that they have not logged out of.
+type SessionExport struct {
+	ClientIp    string `json:"client_ip"`
+	DateStarted string `json:"started_on"`
+}
+
+// ForcedResendExport describes a confirmation link resent to the customer by an admin, with the reason given.
+type ForcedResendExport struct {
+	Email      string `json:"email"`
+	ResentBy   string `json:"resent_by"`
+	Reason     string `json:"reason"`
+	DateResent string `json:"resent_on"`
+}
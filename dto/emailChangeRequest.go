@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+type EmailChangeRequest struct {
+	NewEmail string `json:"new_email" validate:"required,max=100,ascii,email"`
+	Password string `json:"password" validate:"required,max=64,ascii"`
+}
+
+func (r EmailChangeRequest) Validate() *errs.AppError {
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("Email change request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		if errsArr[0].Field() == "Password" {
+			return errs.NewValidationError("Incorrect password")
+		}
+		return errs.NewValidationError("Invalid email")
+	}
+	return nil
+}
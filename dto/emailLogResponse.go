@@ -0,0 +1,15 @@
+package dto
+
+type EmailLogResponse struct {
+	LogId         int64  `json:"log_id"`
+	Type          string `json:"type"`
+	Recipient     string `json:"recipient"`
+	Template      string `json:"template"`
+	Locale        string `json:"locale"`
+	OutboxId      int64  `json:"outbox_id"`
+	Transport     string `json:"transport"`
+	Status        string `json:"status"`
+	Result        string `json:"result"`
+	MessageId     string `json:"message_id"`
+	DateAttempted string `json:"attempted_on"`
+}
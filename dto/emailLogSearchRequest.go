@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+// EmailLogSearchRequest holds the criteria given by an admin to search the email log with, all of them optional.
+type EmailLogSearchRequest struct {
+	Recipient string `validate:"omitempty,max=100"`
+	Template  string `validate:"omitempty,max=50"`
+	Type      string `validate:"omitempty,oneof=direct outbox"`
+	Status    string `validate:"omitempty,oneof=sent failed"`
+	MessageId string `validate:"omitempty,max=255"`
+	From      string `validate:"omitempty,datetime=2006-01-02"`
+	To        string `validate:"omitempty,datetime=2006-01-02"`
+}
+
+func (r EmailLogSearchRequest) Validate() *errs.AppError {
+	errMsg := map[string]string{
+		"Recipient": "Invalid recipient",
+		"Template":  "Invalid template",
+		"Type":      "Invalid type",
+		"Status":    "Invalid status",
+		"MessageId": "Invalid message ID",
+		"From":      "Invalid date",
+		"To":        "Invalid date",
+	}
+
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("Email log search request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		return errs.NewValidationError(errMsg[errsArr[0].Field()])
+	}
+
+	return nil
+}
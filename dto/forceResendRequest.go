@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+// ForceResendRequest is for an admin to resend the confirmation link of a registration regardless of the limits on
+// resending, with the reason recorded for auditing.
+type ForceResendRequest struct {
+	Email  string `json:"email" validate:"required,max=100,ascii,email"`
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+func (r ForceResendRequest) Validate() *errs.AppError {
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("Force resend request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		if errsArr[0].Field() == "Reason" {
+			return errs.NewValidationError("Reason must be given and at most 255 characters long")
+		}
+		return errs.NewValidationError("Invalid email")
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+type ForgotUsernameRequest struct {
+	Email string `json:"email" validate:"required,max=100,ascii,email"`
+}
+
+func (r ForgotUsernameRequest) Validate() *errs.AppError {
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("Forgot username request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		return errs.NewValidationError("Invalid email")
+	}
+	return nil
+}
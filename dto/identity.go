@@ -0,0 +1,8 @@
+package dto
+
+// Identity is the client making a request, as given by the claims of their access token.
+type Identity struct {
+	Username   string
+	Role       string
+	CustomerId string
+}
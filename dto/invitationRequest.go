@@ -0,0 +1,54 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+type InvitationRequest struct {
+	Email  string `json:"email" validate:"required,max=100,ascii,email"`
+	Role   string `json:"role" validate:"required,max=20"`                       //one of the roles without a customer ID
+	Locale string `json:"locale" validate:"omitempty,max=35,bcp47_language_tag"` //language of the invitation email
+}
+
+func (r InvitationRequest) Validate() *errs.AppError {
+	errMsg := map[string]string{
+		"Email":  "Invalid email",
+		"Role":   "Role cannot be given by invitation",
+		"Locale": "Please check that the Language selected is correct.",
+	}
+
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("Invitation request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		return errs.NewValidationError(errMsg[errsArr[0].Field()])
+	}
+	return nil
+}
+
+// AcceptInvitationRequest uses the same validation of username and password as RegistrationRequest.
+type AcceptInvitationRequest struct {
+	Token    string `json:"one_time_token" validate:"required"`
+	Username string `json:"username" validate:"un"`
+	Password string `json:"password" validate:"required,min=12,max=64,ascii"`
+	TotpCode string `json:"totp_code" validate:"required,len=6,numeric"`
+}
+
+func (r AcceptInvitationRequest) Validate() *errs.AppError {
+	errMsg := map[string]string{
+		"Token":    errs.MessageMissingToken,
+		"Username": "Please check that the Username meets the requirements.",
+		"Password": "Please check that the Password meets the requirements.",
+		"TotpCode": "Please check that the code from the authenticator app is correct.",
+	}
+
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("Accept invitation request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		return errs.NewValidationError(errMsg[errsArr[0].Field()])
+	}
+
+	return nil
+}
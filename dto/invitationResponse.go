@@ -0,0 +1,13 @@
+package dto
+
+type InvitationResponse struct {
+	InvitationId string `json:"invitation_id"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	DateExpires  string `json:"expires_on"`
+}
+
+type MfaEnrolmentResponse struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"otpauth_uri"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+type IpAccessRuleRemovalRequest struct {
+	RuleId   int64  `json:"rule_id" validate:"required,min=1"`
+	ClientIp string `json:"-"` //of the admin, resolved from the request
+}
+
+func (r IpAccessRuleRemovalRequest) Validate() *errs.AppError {
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("IP access rule removal request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		return errs.NewValidationError("Invalid rule ID")
+	}
+	return nil
+}
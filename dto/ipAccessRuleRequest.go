@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+// IpAccessRuleRequest is for an admin to add a rule allowing or denying a role access from a network, to a route or
+// to all routes if no route name is given.
+type IpAccessRuleRequest struct {
+	Role      string `json:"role" validate:"required,oneof=admin user"`
+	RouteName string `json:"route_name" validate:"max=50"`
+	Cidr      string `json:"cidr" validate:"required,max=50"` //or a single IP address
+	Action    string `json:"action" validate:"required,oneof=allow deny"`
+	ClientIp  string `json:"-"` //of the admin, resolved from the request
+}
+
+func (r IpAccessRuleRequest) Validate() *errs.AppError {
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("IP access rule request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		switch errsArr[0].Field() {
+		case "Role":
+			return errs.NewValidationError("Role must be admin or user")
+		case "RouteName":
+			return errs.NewValidationError("Route name must be at most 50 characters long")
+		case "Action":
+			return errs.NewValidationError("Action must be allow or deny")
+		default:
+			return errs.NewValidationError("Invalid CIDR")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package dto
+
+type IpAccessRuleResponse struct {
+	RuleId      int64  `json:"rule_id"`
+	Role        string `json:"role"`
+	RouteName   string `json:"route_name"`
+	Cidr        string `json:"cidr"`
+	Action      string `json:"action"`
+	CreatedBy   string `json:"created_by"`
+	DateCreated string `json:"created_on"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+type KycDocumentResponse struct {
+	DocumentId   string `json:"document_id"`
+	DocumentType string `json:"document_type"`
+	FileName     string `json:"file_name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size_bytes"`
+	Checksum     string `json:"checksum_sha256"`
+	DateUploaded string `json:"uploaded_on"`
+}
+
+type KycResponse struct {
+	Email     string                `json:"email"`
+	KycStatus string                `json:"kyc_status"`
+	Reason    string                `json:"reason"`
+	Documents []KycDocumentResponse `json:"documents"`
+}
@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+type KycUploadRequest struct {
+	TokenString  string `validate:"required"`
+	DocumentType string `validate:"required,oneof=passport national_id drivers_license proof_of_address"`
+	FileName     string `validate:"max=255"`
+	Content      []byte
+}
+
+func (r KycUploadRequest) Validate() *errs.AppError {
+	errMsg := map[string]string{
+		"TokenString":  errs.MessageMissingToken,
+		"DocumentType": "Please check that the Document Type selected is correct.",
+		"FileName":     "Please check that the File Name is at most 255 characters long.",
+	}
+
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("KYC upload request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		return errs.NewValidationError(errMsg[errsArr[0].Field()])
+	}
+
+	return nil
+}
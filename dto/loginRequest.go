@@ -10,6 +10,8 @@ import (
 type LoginRequest struct {
 	Username string `json:"username" validate:"required,max=20,ascii"`
 	Password string `json:"password" validate:"required,max=64,ascii"`
+	TotpCode string `json:"totp_code" validate:"omitempty,len=6,numeric"` //only for users who enrolled MFA
+	ClientIp string `json:"-"`                                            //resolved from the request, not given in it
 }
 
 func (r LoginRequest) Validate() *errs.AppError {
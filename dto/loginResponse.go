@@ -2,6 +2,7 @@ package dto
 
 type LoginResponse struct {
 	IsPendingConfirmation bool   `json:"is_pending"`
+	IsEmailUndeliverable  bool   `json:"is_email_undeliverable"` //only while pending, the confirmation link cannot be delivered
 	AccessToken           string `json:"access_token"`
 	RefreshToken          string `json:"refresh_token"`
 	Homepage              string `json:"homepage"`
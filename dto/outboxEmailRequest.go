@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+// OutboxEmailRequest is for an admin's action on an email in the outbox, e.g. requeueing it once dead-lettered.
+type OutboxEmailRequest struct {
+	OutboxId int64 `json:"outbox_id" validate:"required,min=1"`
+}
+
+func (r OutboxEmailRequest) Validate() *errs.AppError {
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("Outbox email request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		return errs.NewValidationError("Invalid outbox ID")
+	}
+	return nil
+}
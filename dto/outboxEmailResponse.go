@@ -0,0 +1,14 @@
+package dto
+
+type OutboxEmailResponse struct {
+	OutboxId      int64  `json:"outbox_id"`
+	Recipient     string `json:"recipient"`
+	Locale        string `json:"locale"`
+	Template      string `json:"template"`
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptOn string `json:"next_attempt_on"`
+	LastError     string `json:"last_error"`
+	DateCreated   string `json:"created_on"`
+	DateSent      string `json:"sent_on"`
+}
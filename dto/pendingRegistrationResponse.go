@@ -0,0 +1,13 @@
+package dto
+
+type PendingRegistrationResponse struct {
+	Email             string `json:"email"`
+	Name              string `json:"name"`
+	DateOfBirth       string `json:"date_of_birth"`
+	Country           string `json:"country"`
+	Zipcode           string `json:"zipcode"`
+	Username          string `json:"username"`
+	DateRegistered    string `json:"created_on"`
+	DateEmailVerified string `json:"email_verified_on"`
+	KycStatus         string `json:"kyc_status"`
+}
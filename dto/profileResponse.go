@@ -0,0 +1,20 @@
+package dto
+
+type ProfileResponse struct {
+	CustomerId  string `json:"customer_id"`
+	Name        string `json:"name"`
+	DateOfBirth string `json:"date_of_birth"`
+	Email       string `json:"email"`
+	Country     string `json:"country"`
+	CountryCode string `json:"country_code"`
+	Zipcode     string `json:"zipcode"`
+	Locale      string `json:"locale"`
+}
+
+type ProfileChangeResponse struct {
+	Field       string `json:"field"`
+	OldValue    string `json:"old_value"`
+	NewValue    string `json:"new_value"`
+	ChangedBy   string `json:"changed_by"`
+	DateChanged string `json:"changed_on"`
+}
@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+// ProfileUpdateRequest uses the same validation as RegistrationRequest, except that every field is optional. Fields
+// left empty are not changed. As the name is stored in full and the zipcode is checked against the country, the
+// first and last names have to be given together, and so do the country and zipcode.
+type ProfileUpdateRequest struct {
+	FirstName   string `json:"first_name" validate:"required_with=LastName,omitempty,max=50,ascii,excludesall=0123456789"`
+	LastName    string `json:"last_name" validate:"required_with=FirstName,omitempty,max=50,ascii,excludesall=0123456789"`
+	CountryCode string `json:"country" validate:"required_with=Zipcode,omitempty,max=100,iso3166_1_alpha2"`
+	Zipcode     string `json:"zipcode" validate:"required_with=CountryCode,omitempty,max=10,postcode_iso3166_alpha2_field=CountryCode"`
+	DateOfBirth string `json:"date_of_birth" validate:"omitempty,datetime=2006-01-02"`
+	Locale      string `json:"locale" validate:"omitempty,max=35,bcp47_language_tag"`
+}
+
+func (r ProfileUpdateRequest) Validate() *errs.AppError {
+	errMsg := map[string]string{
+		"FirstName":   "Please check that the First and Last Names are correct.",
+		"LastName":    "Please check that the First and Last Names are correct.",
+		"CountryCode": "Please check that the Country selected is correct.",
+		"Zipcode":     "Please check that the Postal/Zip Code entered is correct.",
+		"DateOfBirth": "Please check that the Date of Birth entered is correct.",
+		"Locale":      "Please check that the Language selected is correct.",
+	}
+
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("Profile update request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		return errs.NewValidationError(errMsg[errsArr[0].Field()])
+	}
+
+	if r == (ProfileUpdateRequest{}) {
+		logger.Error("Profile update request has no fields to update")
+		return errs.NewValidationError("Nothing to update")
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "time"
+
+// RateLimitStatus describes a limit on requests after one was made, for telling clients how many more they can make
+// and when. It is sent in the RateLimit-* and Retry-After headers rather than the body.
+type RateLimitStatus struct {
+	IsAllowed  bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration //until the limit is fully restored
+	RetryAfter time.Duration //until the next request is allowed, only set if this one was not
+}
+
+// IsMoreRestrictiveThan checks whether the RateLimitStatus leaves the client fewer requests than the other one, so
+// that the most restrictive of several limits applying to a request can be reported.
+func (s RateLimitStatus) IsMoreRestrictiveThan(other RateLimitStatus) bool {
+	if s.IsAllowed != other.IsAllowed {
+		return !s.IsAllowed
+	}
+	if !s.IsAllowed {
+		return s.RetryAfter > other.RetryAfter
+	}
+	return s.Remaining < other.Remaining || (s.Remaining == other.Remaining && s.Reset > other.Reset)
+}
@@ -17,6 +17,9 @@ type RegistrationRequest struct {
 
 	Username string `json:"username" validate:"un"`
 	Password string `json:"password" validate:"required,min=12,max=64,ascii"`
+
+	OnboardingOption string `json:"onboarding_option" validate:"omitempty,max=20,alphanum"`
+	Locale           string `json:"locale" validate:"omitempty,max=35,bcp47_language_tag"` //Accept-Language if not given
 }
 
 func (r RegistrationRequest) Validate() *errs.AppError {
@@ -29,6 +32,9 @@ func (r RegistrationRequest) Validate() *errs.AppError {
 		"Email":       "Please check that the Email entered is correct.",
 		"Username":    "Please check that the Username meets the requirements.",
 		"Password":    "Please check that the Password meets the requirements.",
+
+		"OnboardingOption": "Please check that the Onboarding Option selected is correct.",
+		"Locale":           "Please check that the Language selected is correct.",
 	}
 
 	if errsArr := formValidator.Struct(r); errsArr != nil {
@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/formValidator"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+type ReviewRegistrationRequest struct {
+	Email  string `json:"email" validate:"required,max=100,ascii,email"`
+	Reason string `json:"reason" validate:"max=255"`
+}
+
+// Validate checks the review request, where a reason must be given if the registration is to be rejected.
+func (r ReviewRegistrationRequest) Validate(isApproved bool) *errs.AppError {
+	if errsArr := formValidator.Struct(r); errsArr != nil {
+		logger.Error(fmt.Sprintf("Review registration request is invalid (%s) (%s)",
+			errsArr[0].Error(), errsArr[0].ActualTag()))
+		if errsArr[0].Field() == "Reason" {
+			return errs.NewValidationError("Reason must be at most 255 characters long")
+		}
+		return errs.NewValidationError("Invalid email")
+	}
+
+	if !isApproved && r.Reason == "" {
+		logger.Error("No reason given for rejecting registration")
+		return errs.NewValidationError("Reason must be given when rejecting")
+	}
+
+	return nil
+}
@@ -5,4 +5,5 @@ type VerifyRequest struct {
 	RouteName   string
 	CustomerId  string
 	AccountId   string
+	ClientIp    string //of the client of the banking server, given by the banking server
 }
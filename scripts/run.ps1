@@ -18,6 +18,16 @@ $env:DB_HOST = "localhost"
 $env:DB_PORT = "3306"
 $env:DB_NAME = "banking"
 $env:ENCRYPTION_FILEPATH = "keys/private_key.txt"
+$env:REGISTRATION_EXPIRY = "168h"
+$env:REGISTRATION_APPROVAL_REQUIRED = "false"
+$env:KYC_REQUIRED = "false"
+$env:BLOB_STORAGE_PATH = "blobs"
+$env:ERASURE_RETENTION_PERIOD = "720h"
+$env:EMAIL_TEMPLATES_PATH = "domain/templates/email"
+$env:MAIL_TRANSPORT = "smtp"
+$env:MAIL_SMTP_SECURITY = "none"
+$env:MAIL_SMTP_AUTH = "none"
+$env:MAIL_FEEDBACK_SECRET = "feedback-secret"
 
 # Run app
 go run main.go
\ No newline at end of file
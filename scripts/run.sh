@@ -19,6 +19,16 @@ export DB_HOST="localhost"
 export DB_PORT="3306"
 export DB_NAME="banking"
 export ENCRYPTION_FILEPATH="keys/private_key.txt"
+export REGISTRATION_EXPIRY="168h"
+export REGISTRATION_APPROVAL_REQUIRED="false"
+export KYC_REQUIRED="false"
+export BLOB_STORAGE_PATH="blobs"
+export ERASURE_RETENTION_PERIOD="720h"
+export EMAIL_TEMPLATES_PATH="domain/templates/email"
+export MAIL_TRANSPORT="smtp"
+export MAIL_SMTP_SECURITY="none"
+export MAIL_SMTP_AUTH="none"
+export MAIL_FEEDBACK_SECRET="feedback-secret"
 
 # Run app
 go run main.go
\ No newline at end of file
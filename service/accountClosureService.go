@@ -0,0 +1,98 @@
+package service
+
+import (
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"time"
+)
+
+type AccountClosureService interface { //service (primary port)
+	CloseAccount(dto.AccountClosureRequest, *dto.Identity) (*dto.AccountClosureResponse, *errs.AppError)
+	GetPendingClosures() ([]dto.AccountClosureResponse, *errs.AppError)
+	EraseCustomerData(dto.CustomerActionRequest, string) (*dto.ErasureCertificateResponse, *errs.AppError)
+	PlaceRetentionHold(dto.CustomerActionRequest, string) *errs.AppError
+	ReleaseRetentionHold(dto.CustomerActionRequest, string) *errs.AppError
+}
+
+type DefaultAccountClosureService struct { //business/domain object
+	authRepo    domain.AuthRepository
+	closureRepo domain.AccountClosureRepository
+}
+
+func NewDefaultAccountClosureService(authRepo domain.AuthRepository, closureRepo domain.AccountClosureRepository) DefaultAccountClosureService {
+	return DefaultAccountClosureService{authRepo, closureRepo}
+}
+
+// CloseAccount re-authenticates the logged-in customer with the password in the given dto.AccountClosureRequest,
+// then records their request to close their login and ends all of their sessions. From then on, the customer can no
+// longer log in and their tokens are no longer accepted. Their data is kept until it is erased by an admin once the
+// retention period is over.
+func (s DefaultAccountClosureService) CloseAccount(request dto.AccountClosureRequest, identity *dto.Identity) (*dto.AccountClosureResponse, *errs.AppError) {
+	if err := checkIsCustomer(identity); err != nil {
+		return nil, err
+	}
+
+	auth, err := s.authRepo.Authenticate(identity.Username, request.Password)
+	if err != nil {
+		return nil, err
+	}
+	if auth.CustomerId.String != identity.CustomerId {
+		logger.Error("Customer ID of re-authenticated user does not match that of the access token")
+		return nil, errs.NewAuthorizationError("Access denied")
+	}
+
+	closure, err := domain.NewAccountClosure(identity.CustomerId, identity.Username, request.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.closureRepo.Save(*closure, identity.Username); err != nil {
+		return nil, err
+	}
+
+	response := closure.ToDTO()
+	return &response, nil
+}
+
+// GetPendingClosures retrieves all closed logins whose customer data has not been erased yet.
+func (s DefaultAccountClosureService) GetPendingClosures() ([]dto.AccountClosureResponse, *errs.AppError) {
+	closures, err := s.closureRepo.FindPending()
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.AccountClosureResponse, 0)
+	for _, c := range closures {
+		response = append(response, c.ToDTO())
+	}
+	return response, nil
+}
+
+// EraseCustomerData erases the data held about the customer in the given request, on behalf of the given admin,
+// if the customer closed their login at least domain.GetErasureRetentionPeriod ago and no retention hold is in place.
+// It returns the erasure certificate.
+func (s DefaultAccountClosureService) EraseCustomerData(request dto.CustomerActionRequest, admin string) (*dto.ErasureCertificateResponse, *errs.AppError) {
+	closure, err := s.closureRepo.Erase(request.CustomerId, admin, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	return closure.ToCertificateDTO(), nil
+}
+
+// PlaceRetentionHold prevents the data of the customer in the given request from being erased, for the reason given,
+// until the hold is released.
+func (s DefaultAccountClosureService) PlaceRetentionHold(request dto.CustomerActionRequest, admin string) *errs.AppError {
+	return s.closureRepo.PlaceHold(domain.RetentionHold{
+		CustomerId: request.CustomerId,
+		Reason:     request.Reason,
+		PlacedBy:   admin,
+		DatePlaced: time.Now().UTC().Format(domain.FormatDateTime),
+	})
+}
+
+// ReleaseRetentionHold releases the retention hold in place for the customer in the given request.
+func (s DefaultAccountClosureService) ReleaseRetentionHold(request dto.CustomerActionRequest, admin string) *errs.AppError {
+	return s.closureRepo.ReleaseHold(request.CustomerId, admin, time.Now().UTC().Format(domain.FormatDateTime))
+}
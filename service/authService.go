@@ -4,6 +4,8 @@ import (
 	"github.com/aliciatay-zls/banking-auth/domain"
 	"github.com/aliciatay-zls/banking-auth/dto"
 	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"time"
 )
 
 type AuthService interface { //service (primary port)
@@ -12,6 +14,7 @@ type AuthService interface { //service (primary port)
 	Verify(dto.VerifyRequest) *errs.AppError
 	Refresh(dto.TokenStrings) (*dto.RefreshResponse, *errs.AppError)
 	CheckAlreadyLoggedIn(dto.TokenStrings) (*dto.ContinueResponse, *errs.AppError)
+	Authenticate(string) (*dto.Identity, *errs.AppError)
 }
 
 type DefaultAuthService struct { //business/domain object
@@ -19,15 +22,20 @@ type DefaultAuthService struct { //business/domain object
 	registrationRepo domain.RegistrationRepository //additionally depends on another repo (is a field)
 	rolePermissions  domain.RolePermissions        //additionally depends on another business/domain object (is a field)
 	tokenRepo        domain.TokenRepository        //additionally depends on another repo (is a field)
+	closureRepo      domain.AccountClosureRepository
+	suppressionRepo  domain.EmailSuppressionRepository
+	ipAccessList     *domain.IpAccessList
 }
 
-func NewDefaultAuthService(authRepo domain.AuthRepository, regRepo domain.RegistrationRepository, rp domain.RolePermissions, tokenRepo domain.TokenRepository) DefaultAuthService {
-	return DefaultAuthService{authRepo, regRepo, rp, tokenRepo}
+func NewDefaultAuthService(authRepo domain.AuthRepository, regRepo domain.RegistrationRepository, rp domain.RolePermissions, tokenRepo domain.TokenRepository, closureRepo domain.AccountClosureRepository, suppressionRepo domain.EmailSuppressionRepository, ipAccessList *domain.IpAccessList) DefaultAuthService {
+	return DefaultAuthService{authRepo, regRepo, rp, tokenRepo, closureRepo, suppressionRepo, ipAccessList}
 }
 
 // Login authenticates the client's credentials, generating and sending back a new pair of access and refresh tokens.
 // If not authenticated, it checks if the client has registered before, in which case it informs the client that
-// the registration is pending email confirmation.
+// the registration is pending email confirmation, or that it is pending review or was rejected by an admin. While
+// pending confirmation, the client is also told if emails to the registered address bounced or were marked as spam,
+// as the confirmation link will not reach them.
 func (s DefaultAuthService) Login(request dto.LoginRequest) (*dto.LoginResponse, *errs.AppError) { //business/domain object implements service
 	var auth *domain.Auth
 	var appErr, authErr *errs.AppError
@@ -38,12 +46,29 @@ func (s DefaultAuthService) Login(request dto.LoginRequest) (*dto.LoginResponse,
 		if err != nil {
 			return nil, err
 		}
-		if registration != nil {
-			ott, genErr := s.tokenRepo.BuildToken(registration.GetOneTimeTokenClaims())
+		if registration != nil && !registration.IsExpired() {
+			if registration.IsPendingReview() || registration.IsRejected() {
+				return nil, errs.NewAuthorizationError(registration.GetStatusMessage())
+			}
+
+			//not recorded as an issued one-time token, so it can only be used to resend the confirmation link
+			claims, genErr := registration.GetOneTimeTokenClaims()
+			if genErr != nil {
+				return nil, genErr
+			}
+			ott, genErr := s.tokenRepo.BuildToken(claims)
+			if genErr != nil {
+				return nil, genErr
+			}
+			isSuppressed, genErr := s.suppressionRepo.IsSuppressed(registration.Email)
 			if genErr != nil {
-				return nil, err
+				return nil, genErr
 			}
-			return &dto.LoginResponse{IsPendingConfirmation: true, AccessToken: ott}, nil
+			return &dto.LoginResponse{
+				IsPendingConfirmation: true,
+				IsEmailUndeliverable:  isSuppressed,
+				AccessToken:           ott,
+			}, nil
 		}
 		return nil, authErr
 	}
@@ -51,6 +76,15 @@ func (s DefaultAuthService) Login(request dto.LoginRequest) (*dto.LoginResponse,
 	if !auth.IsRoleValid() {
 		return nil, errs.NewUnexpectedError("Unexpected server-side error")
 	}
+	if appErr = s.checkNotClosed(auth.Username, auth.Role); appErr != nil {
+		return nil, appErr
+	}
+	if appErr = s.ipAccessList.Check(auth.Role, domain.IpAccessRouteLogin, request.ClientIp); appErr != nil {
+		return nil, appErr
+	}
+	if appErr = s.checkMfaCode(auth.Username, request.TotpCode); appErr != nil {
+		return nil, appErr
+	}
 
 	accessClaims := auth.AsAccessTokenClaims()
 	var accessToken, refreshToken string
@@ -63,7 +97,7 @@ func (s DefaultAuthService) Login(request dto.LoginRequest) (*dto.LoginResponse,
 	}
 
 	//hash before inserting to reduce and fix length of refresh token to 64 bytes (hex) for easier storage
-	if appErr = s.authRepo.SaveRefreshTokenToStore(s.tokenRepo.GetHash(refreshToken)); appErr != nil {
+	if appErr = s.authRepo.SaveRefreshTokenToStore(s.tokenRepo.GetHash(refreshToken), auth.Username, request.ClientIp); appErr != nil {
 		return nil, appErr
 	}
 
@@ -95,7 +129,9 @@ func (s DefaultAuthService) Logout(refreshToken string) *errs.AppError {
 }
 
 // Verify uses the claims from the given token string to check that the token is valid and non-expired.
-// It then checks the client's role privileges to access the route and if allowed, the client's identity.
+// It then checks that the client can access the route from its IP address given by the banking server, which is
+// denied if the IP address is missing while IP access rules apply to the client's role and the route. Lastly, it
+// checks the client's role privileges to access the route and if allowed, the client's identity.
 func (s DefaultAuthService) Verify(request dto.VerifyRequest) *errs.AppError { //business/domain object implements service
 	c, appErr := s.tokenRepo.GetClaimsFromToken(request.TokenString, domain.TokenTypeAccess)
 	if appErr != nil {
@@ -105,6 +141,12 @@ func (s DefaultAuthService) Verify(request dto.VerifyRequest) *errs.AppError { /
 	if appErr = accessClaims.Validate(false); appErr != nil {
 		return appErr
 	}
+	if appErr = s.checkNotClosed(accessClaims.Username, accessClaims.Role); appErr != nil {
+		return appErr
+	}
+	if appErr = s.ipAccessList.Check(accessClaims.Role, request.RouteName, request.ClientIp); appErr != nil {
+		return appErr
+	}
 
 	//admin can access all routes (get role from token claims)
 	//user can only access some routes
@@ -184,6 +226,65 @@ func (s DefaultAuthService) CheckAlreadyLoggedIn(tokenStrings dto.TokenStrings)
 	return &dto.ContinueResponse{Homepage: homepage}, nil
 }
 
+// Authenticate checks that the given access token is valid and non-expired, and returns the identity of the client
+// it was issued to. It is used to protect routes of the auth server itself.
+func (s DefaultAuthService) Authenticate(tokenString string) (*dto.Identity, *errs.AppError) {
+	c, appErr := s.tokenRepo.GetClaimsFromToken(tokenString, domain.TokenTypeAccess)
+	if appErr != nil {
+		return nil, appErr
+	}
+	accessClaims := c.(*domain.AccessTokenClaims)
+	if appErr = accessClaims.Validate(false); appErr != nil {
+		return nil, appErr
+	}
+	if appErr = s.checkNotClosed(accessClaims.Username, accessClaims.Role); appErr != nil {
+		return nil, appErr
+	}
+
+	return accessClaims.ToIdentityDTO(), nil
+}
+
+// checkMfaCode ensures that, if the user with the given username has enrolled a TOTP authenticator, the given code
+// from it is correct and has not been used before.
+func (s DefaultAuthService) checkMfaCode(username string, code string) *errs.AppError {
+	secret, appErr := s.authRepo.FindTotpSecret(username)
+	if appErr != nil {
+		return appErr
+	}
+	if secret == "" {
+		return nil
+	}
+
+	if code == "" {
+		logger.Error("No TOTP code given by user who enrolled MFA")
+		return errs.NewAuthenticationError("MFA code required")
+	}
+	step, ok := domain.VerifyTotpCode(secret, code, time.Now().UTC())
+	if !ok {
+		logger.Error("Incorrect TOTP code given")
+		return errs.NewAuthenticationError("Incorrect username, password or MFA code")
+	}
+	return s.authRepo.UseTotpStep(username, step)
+}
+
+// checkNotClosed ensures that the user with the given username and role has not closed their login, in which case
+// any tokens still held by the client are no longer accepted. Admins cannot close their login.
+func (s DefaultAuthService) checkNotClosed(username string, role string) *errs.AppError {
+	if role != domain.RoleUser {
+		return nil
+	}
+
+	isClosed, appErr := s.closureRepo.IsClosed(username)
+	if appErr != nil {
+		return appErr
+	}
+	if isClosed {
+		logger.Error("Login of user was closed")
+		return errs.NewAuthorizationError("Account closed")
+	}
+	return nil
+}
+
 // areTokensValid gets the claims for each token and checks that each are valid, before checking if both tokens
 // belong to the same person using their private claims. This function always considers an expired refresh token to
 // be invalid.
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+type DataExportService interface { //service (primary port)
+	RequestExport(*dto.Identity) (*dto.DataExportResponse, *errs.AppError)
+	DownloadExport(string) ([]byte, *errs.AppError)
+	Cleanup()
+}
+
+type DefaultDataExportService struct { //business/domain object
+	exportRepo       domain.DataExportRepository
+	authRepo         domain.AuthRepository
+	customerRepo     domain.CustomerRepository
+	registrationRepo domain.RegistrationRepository
+	kycRepo          domain.KycRepository
+	ottRepo          domain.OneTimeTokenRepository
+	blobRepo         domain.BlobRepository
+	emailLogRepo     domain.EmailLogRepository
+	tokenRepo        domain.TokenRepository
+}
+
+func NewDefaultDataExportService(exportRepo domain.DataExportRepository, authRepo domain.AuthRepository, customerRepo domain.CustomerRepository, regRepo domain.RegistrationRepository, kycRepo domain.KycRepository, ottRepo domain.OneTimeTokenRepository, blobRepo domain.BlobRepository, emailLogRepo domain.EmailLogRepository, tokenRepo domain.TokenRepository) DefaultDataExportService {
+	return DefaultDataExportService{exportRepo, authRepo, customerRepo, regRepo, kycRepo, ottRepo, blobRepo, emailLogRepo, tokenRepo}
+}
+
+// RequestExport records a request by the logged-in customer for an archive of the data held about them, then
+// generates it in the background. Once generated, a one-time link to download it is emailed to the customer.
+// Only one export can be in progress for a customer at a time.
+func (s DefaultDataExportService) RequestExport(identity *dto.Identity) (*dto.DataExportResponse, *errs.AppError) {
+	if err := checkIsCustomer(identity); err != nil {
+		return nil, err
+	}
+
+	export, err := domain.NewDataExport(identity.CustomerId, identity.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.exportRepo.Save(*export); err != nil {
+		return nil, err
+	}
+
+	go s.generate(*export, *identity)
+
+	return export.ToDTO(), nil
+}
+
+// generate builds the archive for the given DataExport and stores it in the blob store, then saves the one-time token
+// of the link to download it along with the email delivering the link, which goes through the outbox. The DataExport
+// is marked as failed if any of these steps fail.
+func (s DefaultDataExportService) generate(export domain.DataExport, identity dto.Identity) {
+	status := domain.DataExportStatusFailed
+	defer func() {
+		_ = s.exportRepo.UpdateStatus(export.ExportId, status, time.Now().UTC().Format(domain.FormatDateTime)) //error already logged
+	}()
+
+	customer, err := s.customerRepo.FindById(export.CustomerId)
+	if err != nil {
+		return
+	}
+	archive, err := s.buildArchive(*customer, identity)
+	if err != nil {
+		return
+	}
+	content, jsonErr := json.MarshalIndent(archive, "", "  ")
+	if jsonErr != nil {
+		logger.Error("Error while marshalling data export archive: " + jsonErr.Error())
+		return
+	}
+	if err = s.blobRepo.Put(export.StorageKey, content); err != nil {
+		return
+	}
+
+	claims, err := export.GetDataExportTokenClaims()
+	if err != nil {
+		return
+	}
+	ott, err := s.tokenRepo.BuildToken(claims)
+	if err != nil {
+		return
+	}
+	email, err := domain.NewOutboxEmail(customer.Email, customer.Locale, domain.EmailTemplateDataExport,
+		map[string]any{"Link": buildDataExportDownloadURL(ott)})
+	if err != nil {
+		return
+	}
+	if err = s.ottRepo.Save(claims.ID, customer.Email, domain.OneTimeTokenPurposeDataExport, claims.ExpiresAt.Time, *email); err != nil {
+		return
+	}
+
+	status = domain.DataExportStatusReady
+}
+
+// buildArchive collects the data held about the given customer: their user, profile, registration, KYC documents,
+// active sessions, the emails with one-time links sent to them, the log of all emails sent to them, the confirmation
+// links force resent to them and the audit trail of their profile. Passwords and tokens are left out. No login
+// history beyond the active sessions, nor consents, are kept by the auth server.
+func (s DefaultDataExportService) buildArchive(customer domain.Customer, identity dto.Identity) (*dto.DataExportArchive, *errs.AppError) {
+	archive := dto.DataExportArchive{
+		DateGenerated:  time.Now().UTC().Format(domain.FormatDateTime),
+		User:           identity,
+		Profile:        customer.ToProfileDTO(),
+		KycDocuments:   make([]dto.KycDocumentResponse, 0),
+		ActiveSessions: make([]dto.SessionExport, 0),
+		EmailsSent:     make([]dto.OneTimeTokenExport, 0),
+		EmailLog:       make([]dto.EmailLogResponse, 0),
+		ForcedResends:  make([]dto.ForcedResendExport, 0),
+		ProfileChanges: make([]dto.ProfileChangeResponse, 0),
+	}
+
+	registration, err := s.registrationRepo.FindFromEmail(customer.Email)
+	if err != nil && err.Code != http.StatusNotFound { //customers created before sign-up was available have no registration
+		return nil, err
+	}
+	if registration != nil {
+		archive.Registration = registration.ToExportDTO()
+	}
+
+	docs, err := s.kycRepo.FindAll(customer.Email)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range docs {
+		archive.KycDocuments = append(archive.KycDocuments, d.ToDTO())
+	}
+
+	sessions, err := s.authRepo.FindSessions(identity.Username)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		archive.ActiveSessions = append(archive.ActiveSessions, session.ToExportDTO())
+	}
+
+	tokens, err := s.ottRepo.FindAllFromEmail(customer.Email)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		archive.EmailsSent = append(archive.EmailsSent, t.ToExportDTO())
+	}
+
+	entries, err := s.emailLogRepo.FindAllFromRecipient(customer.Email)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		archive.EmailLog = append(archive.EmailLog, e.ToDTO())
+	}
+
+	resends, err := s.registrationRepo.FindForcedResends(customer.Email)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resends {
+		archive.ForcedResends = append(archive.ForcedResends, r.ToExportDTO())
+	}
+
+	changes, err := s.customerRepo.FindProfileChanges(customer.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		archive.ProfileChanges = append(archive.ProfileChanges, c.ToDTO())
+	}
+
+	return &archive, nil
+}
+
+func buildDataExportDownloadURL(ott string) string {
+	u := url.URL{
+		Scheme: "https",
+		Host:   os.Getenv("SERVER_DOMAIN"),
+		Path:   "auth/me/export/download",
+	}
+
+	v := url.Values{}
+	v.Add("ott", ott)
+	u.RawQuery = v.Encode()
+
+	return u.String()
+}
+
+// DownloadExport uses the given token's claims to check that it is valid and fetches the archive of the DataExport,
+// then uses the token up so that the link cannot be used again before returning the archive. The link still works if
+// the archive could not be fetched.
+func (s DefaultDataExportService) DownloadExport(tokenString string) ([]byte, *errs.AppError) {
+	c, err := s.tokenRepo.GetClaimsFromToken(tokenString, domain.TokenTypeDataExport)
+	if err != nil {
+		return nil, err
+	}
+	claims := c.(*domain.DataExportTokenClaims)
+	if err = claims.Validate(); err != nil {
+		return nil, err
+	}
+
+	export, err := s.exportRepo.Find(claims.ExportId)
+	if err != nil {
+		return nil, err
+	}
+	if err = export.CheckDownloadable(); err != nil {
+		return nil, err
+	}
+
+	content, err := s.blobRepo.Get(export.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.ottRepo.MarkUsed(claims.ID); err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// Cleanup removes the archives of data exports whose download link has expired or whose generation failed from the
+// blob store, every domain.RegistrationCleanupInterval, indefinitely.
+func (s DefaultDataExportService) Cleanup() {
+	for {
+		time.Sleep(domain.RegistrationCleanupInterval)
+
+		cutoff := time.Now().UTC().Add(-domain.OneTimeTokenDuration).Format(domain.FormatDateTime)
+		exports, err := s.exportRepo.FindExpired(cutoff)
+		if err != nil {
+			continue
+		}
+		for _, e := range exports {
+			if err = s.blobRepo.Delete(e.StorageKey); err != nil {
+				continue
+			}
+			_ = s.exportRepo.UpdateStatus(e.ExportId, domain.DataExportStatusExpired, "") //error already logged, retried in the next round
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type EmailChangeService interface { //service (primary port)
+	RequestEmailChange(dto.EmailChangeRequest, *dto.Identity) *errs.AppError
+	ConfirmEmailChange(string) *errs.AppError
+}
+
+type DefaultEmailChangeService struct { //business/domain object
+	authRepo         domain.AuthRepository
+	customerRepo     domain.CustomerRepository
+	registrationRepo domain.RegistrationRepository
+	tokenRepo        domain.TokenRepository
+	ottRepo          domain.OneTimeTokenRepository
+}
+
+func NewDefaultEmailChangeService(authRepo domain.AuthRepository, customerRepo domain.CustomerRepository, regRepo domain.RegistrationRepository, tokenRepo domain.TokenRepository, ottRepo domain.OneTimeTokenRepository) DefaultEmailChangeService {
+	return DefaultEmailChangeService{authRepo, customerRepo, regRepo, tokenRepo, ottRepo}
+}
+
+// RequestEmailChange re-authenticates the logged-in user with the password in the given dto.EmailChangeRequest and
+// checks that the new email is different and has not been used to register before, as for a new registration.
+// A one-time use JWT is then generated to form a confirmation link which is emailed to the new email, and a notice of
+// the request is sent to the current email. Both emails go through the outbox in the same db transaction that saves
+// the token (invalidating older links), so a link is never sent that the server does not know about. The email is
+// only changed once the link is used.
+func (s DefaultEmailChangeService) RequestEmailChange(request dto.EmailChangeRequest, identity *dto.Identity) *errs.AppError {
+	if err := checkIsCustomer(identity); err != nil {
+		return err
+	}
+
+	auth, err := s.authRepo.Authenticate(identity.Username, request.Password)
+	if err != nil {
+		return err
+	}
+	if auth.CustomerId.String != identity.CustomerId {
+		logger.Error("Customer ID of re-authenticated user does not match that of the access token")
+		return errs.NewAuthorizationError("Access denied")
+	}
+
+	customer, err := s.customerRepo.FindById(identity.CustomerId)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(customer.Email, request.NewEmail) {
+		logger.Error("New email is the same as the current email")
+		return errs.NewValidationError("New email must be different from the current email")
+	}
+	if err = s.registrationRepo.IsEmailUsed(request.NewEmail); err != nil {
+		return err
+	}
+
+	claims, err := customer.GetEmailChangeTokenClaims(request.NewEmail)
+	if err != nil {
+		return err
+	}
+	ott, err := s.tokenRepo.BuildToken(claims)
+	if err != nil {
+		return err
+	}
+	confirmationEmail, err := domain.NewOutboxEmail(request.NewEmail, customer.Locale,
+		domain.EmailTemplateEmailChangeConfirmation, map[string]any{"Link": buildEmailChangeConfirmationURL(ott)})
+	if err != nil {
+		return err
+	}
+	noticeEmail, err := domain.NewOutboxEmail(customer.Email, customer.Locale, domain.EmailTemplateEmailChangeNotice,
+		map[string]any{"NewEmail": request.NewEmail})
+	if err != nil {
+		return err
+	}
+
+	return s.ottRepo.Save(claims.ID, claims.OldEmail, domain.OneTimeTokenPurposeEmailChange, claims.ExpiresAt.Time,
+		*confirmationEmail, *noticeEmail)
+}
+
+func buildEmailChangeConfirmationURL(ott string) string {
+	u := url.URL{
+		Scheme: "https",
+		Host:   os.Getenv("FRONTEND_SERVER_DOMAIN"),
+		Path:   "email/change/confirm",
+	}
+
+	v := url.Values{}
+	v.Add("ott", ott)
+	u.RawQuery = v.Encode()
+
+	return u.String()
+}
+
+// ConfirmEmailChange uses the given token's claims to check that it is valid and has not been used or replaced by a
+// newer link, before changing the email of the customer in one db transaction which also uses up the token. The new
+// email is checked again as it may have been used to register in the meantime.
+func (s DefaultEmailChangeService) ConfirmEmailChange(tokenString string) *errs.AppError {
+	c, err := s.tokenRepo.GetClaimsFromToken(tokenString, domain.TokenTypeEmailChange)
+	if err != nil {
+		return err
+	}
+	claims := c.(*domain.EmailChangeTokenClaims)
+	if err = claims.Validate(); err != nil {
+		return err
+	}
+	if err = s.ottRepo.CheckUsable(claims.ID); err != nil {
+		return err
+	}
+
+	return s.customerRepo.ChangeEmail(claims)
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+)
+
+type EmailLogService interface { //service (primary port)
+	SearchLog(dto.EmailLogSearchRequest) ([]dto.EmailLogResponse, *errs.AppError)
+}
+
+type DefaultEmailLogService struct { //business/domain object
+	logRepo domain.EmailLogRepository
+}
+
+func NewDefaultEmailLogService(logRepo domain.EmailLogRepository) DefaultEmailLogService {
+	return DefaultEmailLogService{logRepo}
+}
+
+// SearchLog retrieves the latest entries of the email log matching all the criteria given in the request.
+func (s DefaultEmailLogService) SearchLog(request dto.EmailLogSearchRequest) ([]dto.EmailLogResponse, *errs.AppError) {
+	entries, err := s.logRepo.Find(request)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.EmailLogResponse, 0)
+	for _, e := range entries {
+		response = append(response, e.ToDTO())
+	}
+	return response, nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"time"
+)
+
+type EmailOutboxService interface { //service (primary port)
+	Deliver()
+	GetOutboxEmails(string) ([]dto.OutboxEmailResponse, *errs.AppError)
+	RequeueEmail(dto.OutboxEmailRequest) *errs.AppError
+}
+
+type DefaultEmailOutboxService struct { //business/domain object
+	outboxRepo domain.EmailOutboxRepository
+	emailRepo  domain.EmailRepository
+}
+
+func NewDefaultEmailOutboxService(outboxRepo domain.EmailOutboxRepository, emailRepo domain.EmailRepository) DefaultEmailOutboxService {
+	return DefaultEmailOutboxService{outboxRepo, emailRepo}
+}
+
+// Deliver runs in the background, sending the emails in the outbox every domain.EmailOutboxPollInterval. Each email
+// is claimed before it is sent so that concurrent workers do not send it twice. Failed attempts are retried with
+// exponential back-off, and emails that still fail after domain.EmailOutboxMaxAttempts are dead-lettered until an
+// admin requeues them.
+func (s DefaultEmailOutboxService) Deliver() {
+	for {
+		time.Sleep(domain.EmailOutboxPollInterval)
+
+		now := time.Now().UTC()
+		emails, err := s.outboxRepo.FindDue(now.Format(domain.FormatDateTime), domain.EmailOutboxBatchSize)
+		if err != nil {
+			continue
+		}
+		for i := range emails {
+			s.deliver(&emails[i], now)
+		}
+	}
+}
+
+// deliver claims and sends the given email, recording the outcome. Errors are already logged and the email is
+// retried in a later round.
+func (s DefaultEmailOutboxService) deliver(email *domain.OutboxEmail, now time.Time) {
+	claimedUntil := now.Add(domain.EmailOutboxClaimDuration).Format(domain.FormatDateTime)
+	if isClaimed, err := s.outboxRepo.Claim(email, claimedUntil); err != nil || !isClaimed {
+		return
+	}
+
+	timeSent, err := s.emailRepo.SendOutboxEmail(*email)
+	if err == nil {
+		_ = s.outboxRepo.MarkSent(email.Id, timeSent)
+		return
+	}
+
+	email.RecordFailure(err.Message, time.Now().UTC())
+	if email.IsDead() {
+		logger.Error(fmt.Sprintf("Email %d to %s dead-lettered after %d attempts", email.Id, email.Recipient, email.Attempts))
+	}
+	_ = s.outboxRepo.UpdateFailure(*email)
+}
+
+// GetOutboxEmails retrieves the latest emails in the outbox with the given status (pending, sent or dead), or with any
+// status if none is given.
+func (s DefaultEmailOutboxService) GetOutboxEmails(status string) ([]dto.OutboxEmailResponse, *errs.AppError) {
+	if status != "" && status != domain.EmailOutboxStatusPending && status != domain.EmailOutboxStatusSent &&
+		status != domain.EmailOutboxStatusDead {
+		logger.Error("Invalid outbox email status in url")
+		return nil, errs.NewValidationError("Invalid status")
+	}
+
+	emails, err := s.outboxRepo.FindAll(status)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.OutboxEmailResponse, 0)
+	for _, e := range emails {
+		response = append(response, e.ToDTO())
+	}
+	return response, nil
+}
+
+// RequeueEmail makes the dead-lettered email given in the request pending again, to be retried from scratch.
+func (s DefaultEmailOutboxService) RequeueEmail(request dto.OutboxEmailRequest) *errs.AppError {
+	return s.outboxRepo.Requeue(request.OutboxId, time.Now().UTC().Format(domain.FormatDateTime))
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+)
+
+type EmailSuppressionService interface { //service (primary port)
+	ProcessReport([]byte) *errs.AppError
+}
+
+type DefaultEmailSuppressionService struct { //business/domain object
+	suppressionRepo domain.EmailSuppressionRepository
+}
+
+func NewDefaultEmailSuppressionService(suppressionRepo domain.EmailSuppressionRepository) DefaultEmailSuppressionService {
+	return DefaultEmailSuppressionService{suppressionRepo}
+}
+
+// ProcessReport adds the addresses that hard-bounced or complained in the given bounce or complaint report to the
+// suppression list, so that no more confirmation links are sent to them. Reports about temporary failures are
+// accepted but change nothing.
+func (s DefaultEmailSuppressionService) ProcessReport(report []byte) *errs.AppError {
+	suppressions, err := domain.ParseFeedbackReport(report)
+	if err != nil {
+		logger.Error("Error while parsing email feedback report: " + err.Error())
+		return errs.NewValidationError("Invalid report")
+	}
+	if len(suppressions) == 0 {
+		return nil
+	}
+
+	if appErr := s.suppressionRepo.Save(suppressions); appErr != nil {
+		return appErr
+	}
+	for _, sup := range suppressions {
+		logger.Info(fmt.Sprintf("Suppressed emails to %s (%s)", sup.Email, sup.Reason))
+	}
+	return nil
+}
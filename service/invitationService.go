@@ -0,0 +1,140 @@
+package service
+
+import (
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"net/url"
+	"os"
+	"time"
+)
+
+type InvitationService interface { //service (primary port)
+	Invite(dto.InvitationRequest, string) (*dto.InvitationResponse, *errs.AppError)
+	EnrolMfa(string) (*dto.MfaEnrolmentResponse, *errs.AppError)
+	AcceptInvitation(dto.AcceptInvitationRequest) *errs.AppError
+}
+
+type DefaultInvitationService struct { //business/domain object
+	invitationRepo domain.InvitationRepository
+	tokenRepo      domain.TokenRepository
+	ottRepo        domain.OneTimeTokenRepository
+}
+
+func NewDefaultInvitationService(invitationRepo domain.InvitationRepository, tokenRepo domain.TokenRepository, ottRepo domain.OneTimeTokenRepository) DefaultInvitationService {
+	return DefaultInvitationService{invitationRepo, tokenRepo, ottRepo}
+}
+
+// Invite records an invitation by the given admin for the email in the given dto.InvitationRequest to become a user
+// with the role in it, then emails a one-time link to accept it through the outbox. Inviting the same email again
+// invalidates the older links.
+func (s DefaultInvitationService) Invite(request dto.InvitationRequest, invitedBy string) (*dto.InvitationResponse, *errs.AppError) {
+	invitation, err := domain.NewInvitation(request.Email, request.Role, invitedBy)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := invitation.GetInvitationTokenClaims()
+	if err != nil {
+		return nil, err
+	}
+	ott, err := s.tokenRepo.BuildToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	email, err := domain.NewOutboxEmail(request.Email, domain.NormaliseLocale(request.Locale), domain.EmailTemplateInvitation,
+		map[string]any{"InvitedBy": invitedBy, "Link": buildInvitationURL(ott)})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.invitationRepo.Save(*invitation, claims, *email); err != nil {
+		return nil, err
+	}
+
+	return invitation.ToDTO(), nil
+}
+
+func buildInvitationURL(ott string) string {
+	u := url.URL{
+		Scheme: "https",
+		Host:   os.Getenv("FRONTEND_SERVER_DOMAIN"),
+		Path:   "invitation/accept",
+	}
+
+	v := url.Values{}
+	v.Add("ott", ott)
+	u.RawQuery = v.Encode()
+
+	return u.String()
+}
+
+// EnrolMfa uses the given token to identify the pending invitation, then generates a new TOTP secret for the invitee
+// to add to their authenticator app. Calling it again replaces the secret, e.g. if the first was not saved.
+func (s DefaultInvitationService) EnrolMfa(tokenString string) (*dto.MfaEnrolmentResponse, *errs.AppError) {
+	claims, invitation, err := s.getPendingInvitation(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := domain.GenerateTotpSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err = s.invitationRepo.UpdateMfaSecret(invitation.InvitationId, secret); err != nil {
+		return nil, err
+	}
+
+	return &dto.MfaEnrolmentResponse{
+		Secret: secret,
+		Uri:    domain.BuildTotpUri(claims.Email, secret),
+	}, nil
+}
+
+// AcceptInvitation uses the token in the given dto.AcceptInvitationRequest to identify the pending invitation and
+// checks the code from the invitee's authenticator app, before creating the new admin with the chosen username and
+// password and their enrolled TOTP secret in one db transaction, which also uses up the token.
+func (s DefaultInvitationService) AcceptInvitation(request dto.AcceptInvitationRequest) *errs.AppError {
+	claims, invitation, err := s.getPendingInvitation(request.Token)
+	if err != nil {
+		return err
+	}
+
+	step, err := invitation.CheckMfaCode(request.TotpCode)
+	if err != nil {
+		return err
+	}
+
+	hashedPw, err := domain.HashAndSaltPassword(request.Password)
+	if err != nil {
+		return err
+	}
+
+	acceptTime := time.Now().UTC().Format(domain.FormatDateTime)
+	return s.invitationRepo.Accept(invitation.InvitationId, claims.ID, request.Username, hashedPw, step, acceptTime)
+}
+
+// getPendingInvitation uses the given token's claims to check that it is valid and has not been used or replaced by
+// a newer invitation, then retrieves the invitation and checks that it has not been accepted yet.
+func (s DefaultInvitationService) getPendingInvitation(tokenString string) (*domain.InvitationTokenClaims, *domain.Invitation, *errs.AppError) {
+	c, err := s.tokenRepo.GetClaimsFromToken(tokenString, domain.TokenTypeInvitation)
+	if err != nil {
+		return nil, nil, err
+	}
+	claims := c.(*domain.InvitationTokenClaims)
+	if err = claims.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err = s.ottRepo.CheckUsable(claims.ID); err != nil {
+		return nil, nil, err
+	}
+
+	invitation, err := s.invitationRepo.Find(claims.InvitationId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = invitation.CheckPending(); err != nil {
+		return nil, nil, err
+	}
+
+	return claims, invitation, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"fmt"
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"time"
+)
+
+type IpAccessService interface { //service (primary port)
+	GetRules() []dto.IpAccessRuleResponse
+	AddRule(dto.IpAccessRuleRequest, string) (*dto.IpAccessRuleResponse, *errs.AppError)
+	RemoveRule(dto.IpAccessRuleRemovalRequest, string) *errs.AppError
+}
+
+type DefaultIpAccessService struct { //business/domain object
+	ruleRepo     domain.IpAccessRuleRepository
+	ipAccessList *domain.IpAccessList
+}
+
+func NewDefaultIpAccessService(ruleRepo domain.IpAccessRuleRepository, ipAccessList *domain.IpAccessList) DefaultIpAccessService {
+	return DefaultIpAccessService{ruleRepo, ipAccessList}
+}
+
+func (s DefaultIpAccessService) GetRules() []dto.IpAccessRuleResponse {
+	response := make([]dto.IpAccessRuleResponse, 0)
+	for _, rule := range s.ipAccessList.GetRules() {
+		response = append(response, rule.ToDTO())
+	}
+	return response
+}
+
+// AddRule adds the rule given in the request on behalf of the given admin. Rules that would stop the admin from
+// logging in from where they are now are refused, so that admins cannot lock themselves out by mistake.
+func (s DefaultIpAccessService) AddRule(request dto.IpAccessRuleRequest, admin string) (*dto.IpAccessRuleResponse, *errs.AppError) {
+	network, err := domain.ParseCidr(request.Cidr)
+	if err != nil {
+		logger.Error("Error while parsing CIDR of IP access rule: " + err.Error())
+		return nil, errs.NewValidationError("Invalid CIDR")
+	}
+	rule := domain.IpAccessRule{
+		Role:        request.Role,
+		RouteName:   request.RouteName,
+		Cidr:        network.String(),
+		Action:      request.Action,
+		CreatedBy:   admin,
+		DateCreated: time.Now().UTC().Format(domain.FormatDateTime),
+	}
+
+	if appErr := checkNotLockedOut(append(s.ipAccessList.GetRules(), rule), request.ClientIp); appErr != nil {
+		return nil, appErr
+	}
+
+	savedRule, appErr := s.ruleRepo.Save(rule)
+	if appErr != nil {
+		return nil, appErr
+	}
+	s.reload()
+
+	logger.Info(fmt.Sprintf("Admin %s added IP access rule %d (%s %s from %s)", admin, savedRule.Id, rule.Action,
+		rule.Role, rule.Cidr))
+	response := savedRule.ToDTO()
+	return &response, nil
+}
+
+// RemoveRule removes the rule given in the request on behalf of the given admin, unless it would stop the admin from
+// logging in from where they are now.
+func (s DefaultIpAccessService) RemoveRule(request dto.IpAccessRuleRemovalRequest, admin string) *errs.AppError {
+	remainingRules := make([]domain.IpAccessRule, 0)
+	for _, rule := range s.ipAccessList.GetRules() {
+		if rule.Id != request.RuleId {
+			remainingRules = append(remainingRules, rule)
+		}
+	}
+	if appErr := checkNotLockedOut(remainingRules, request.ClientIp); appErr != nil {
+		return appErr
+	}
+
+	if appErr := s.ruleRepo.Delete(request.RuleId); appErr != nil {
+		return appErr
+	}
+	s.reload()
+
+	logger.Info(fmt.Sprintf("Admin %s removed IP access rule %d", admin, request.RuleId))
+	return nil
+}
+
+// reload makes changes take effect on this instance of the server right away, instead of at the next periodic reload.
+func (s DefaultIpAccessService) reload() {
+	if appErr := s.ipAccessList.Reload(); appErr != nil {
+		logger.Error("Error while reloading IP access rules after change, will retry periodically")
+	}
+}
+
+// checkNotLockedOut ensures that the given rules still let an admin log in and change the rules from the given IP
+// address.
+func checkNotLockedOut(rules []domain.IpAccessRule, clientIp string) *errs.AppError {
+	for _, route := range []string{domain.IpAccessRouteLogin, domain.IpAccessRouteAddRule, domain.IpAccessRouteRemoveRule} {
+		if !domain.IsIpAllowedByRules(rules, domain.RoleAdmin, route, clientIp) {
+			logger.Error("Change to IP access rules would lock out the admin making it")
+			return errs.NewValidationError("Change would block your own access")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"time"
+)
+
+type KycService interface { //service (primary port)
+	UploadDocument(dto.KycUploadRequest) (*dto.KycDocumentResponse, *errs.AppError)
+	GetKyc(string) (*dto.KycResponse, *errs.AppError)
+	GetDocument(string, string) (*dto.KycDocumentResponse, []byte, *errs.AppError)
+	ReviewKyc(dto.ReviewRegistrationRequest, bool) *errs.AppError
+	Cleanup()
+}
+
+type DefaultKycService struct { //business/domain object
+	kycRepo          domain.KycRepository
+	registrationRepo domain.RegistrationRepository
+	blobRepo         domain.BlobRepository
+	tokenRepo        domain.TokenRepository
+}
+
+func NewDefaultKycService(kycRepo domain.KycRepository, regRepo domain.RegistrationRepository, blobRepo domain.BlobRepository, tokenRepo domain.TokenRepository) DefaultKycService {
+	return DefaultKycService{kycRepo, regRepo, blobRepo, tokenRepo}
+}
+
+// UploadDocument uses the claims of the one-time token in the request to identify the Registration, and checks that
+// documents can still be uploaded for it. The document is then validated and stored in the blob store, with its
+// metadata saved to the db. The blob is removed again if the metadata cannot be saved.
+func (s DefaultKycService) UploadDocument(request dto.KycUploadRequest) (*dto.KycDocumentResponse, *errs.AppError) {
+	c, err := s.tokenRepo.GetClaimsFromToken(request.TokenString, domain.TokenTypeOneTime)
+	if err != nil {
+		return nil, err
+	}
+	claims := c.(*domain.OneTimeTokenClaims)
+	if err = claims.CheckExpiry(); err != nil {
+		return nil, err
+	}
+
+	registration, err := s.registrationRepo.FindFromEmail(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if err = registration.CanUploadKycDocuments(); err != nil {
+		return nil, err
+	}
+
+	doc, err := domain.NewKycDocument(registration.Email, request.DocumentType, request.FileName, request.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.blobRepo.Put(doc.StorageKey, request.Content); err != nil {
+		return nil, err
+	}
+	if err = s.kycRepo.Save(*doc); err != nil {
+		if deleteErr := s.blobRepo.Delete(doc.StorageKey); deleteErr != nil {
+			logger.Error("Error while removing KYC document after failing to save it: " + deleteErr.Message)
+		}
+		return nil, err
+	}
+
+	response := doc.ToDTO()
+	return &response, nil
+}
+
+// GetKyc retrieves the KYC status of the Registration made using the given email, along with the metadata of all
+// documents uploaded for it.
+func (s DefaultKycService) GetKyc(email string) (*dto.KycResponse, *errs.AppError) {
+	registration, err := s.registrationRepo.FindFromEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := s.kycRepo.FindAll(email)
+	if err != nil {
+		return nil, err
+	}
+
+	response := dto.KycResponse{
+		Email:     registration.Email,
+		KycStatus: registration.KycStatus,
+		Reason:    registration.KycReviewReason.String,
+		Documents: make([]dto.KycDocumentResponse, 0),
+	}
+	for _, d := range docs {
+		response.Documents = append(response.Documents, d.ToDTO())
+	}
+	return &response, nil
+}
+
+// GetDocument retrieves the metadata and content of the KYC document with the given ID uploaded for the Registration
+// made using the given email, checking that the content has not changed since it was uploaded.
+func (s DefaultKycService) GetDocument(email string, documentId string) (*dto.KycDocumentResponse, []byte, *errs.AppError) {
+	doc, err := s.kycRepo.Find(email, documentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.blobRepo.Get(doc.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = doc.CheckIntegrity(content); err != nil {
+		return nil, nil, err
+	}
+
+	response := doc.ToDTO()
+	return &response, content, nil
+}
+
+// ReviewKyc marks the documents submitted for the Registration given in the request as verified or rejected.
+// A rejected Registration can have new documents uploaded for it, which need to be checked again.
+func (s DefaultKycService) ReviewKyc(request dto.ReviewRegistrationRequest, isVerified bool) *errs.AppError {
+	status := domain.KycStatusRejected
+	if isVerified {
+		status = domain.KycStatusVerified
+	}
+
+	return s.kycRepo.UpdateStatus(request.Email, status, request.Reason)
+}
+
+// Cleanup removes KYC documents whose Registration no longer exists from both the blob store and the db, every
+// domain.RegistrationCleanupInterval, indefinitely.
+func (s DefaultKycService) Cleanup() {
+	for {
+		time.Sleep(domain.RegistrationCleanupInterval)
+
+		docs, err := s.kycRepo.FindOrphaned()
+		if err != nil {
+			continue
+		}
+		for _, d := range docs {
+			if err = s.blobRepo.Delete(d.StorageKey); err != nil {
+				continue
+			}
+			_ = s.kycRepo.Delete(d.DocumentId) //error already logged, retried in the next round
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
+	"time"
+)
+
+type ProfileService interface { //service (primary port)
+	GetProfile(*dto.Identity) (*dto.ProfileResponse, *errs.AppError)
+	UpdateProfile(dto.ProfileUpdateRequest, *dto.Identity) (*dto.ProfileResponse, *errs.AppError)
+	GetProfileChanges(string) ([]dto.ProfileChangeResponse, *errs.AppError)
+}
+
+type DefaultProfileService struct { //business/domain object
+	customerRepo domain.CustomerRepository
+}
+
+func NewDefaultProfileService(customerRepo domain.CustomerRepository) DefaultProfileService {
+	return DefaultProfileService{customerRepo}
+}
+
+// GetProfile retrieves the profile of the logged-in customer.
+func (s DefaultProfileService) GetProfile(identity *dto.Identity) (*dto.ProfileResponse, *errs.AppError) {
+	if err := checkIsCustomer(identity); err != nil {
+		return nil, err
+	}
+
+	customer, err := s.customerRepo.FindById(identity.CustomerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return customer.ToProfileDTO(), nil
+}
+
+// UpdateProfile changes the profile of the logged-in customer using the non-empty fields of the given
+// dto.ProfileUpdateRequest. Each field that changed is recorded in the audit trail. The email can only be changed
+// through the email change flow, as the new email needs to be verified.
+func (s DefaultProfileService) UpdateProfile(request dto.ProfileUpdateRequest, identity *dto.Identity) (*dto.ProfileResponse, *errs.AppError) {
+	if err := checkIsCustomer(identity); err != nil {
+		return nil, err
+	}
+
+	changeTime := time.Now().UTC().Format(domain.FormatDateTime)
+	customer, err := s.customerRepo.UpdateProfile(identity.CustomerId, request, identity.Username, changeTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return customer.ToProfileDTO(), nil
+}
+
+// GetProfileChanges retrieves the audit trail of changes to the profile of the customer with the given customer ID.
+func (s DefaultProfileService) GetProfileChanges(customerId string) ([]dto.ProfileChangeResponse, *errs.AppError) {
+	changes, err := s.customerRepo.FindProfileChanges(customerId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.ProfileChangeResponse, 0)
+	for _, c := range changes {
+		response = append(response, c.ToDTO())
+	}
+	return response, nil
+}
+
+// checkIsCustomer ensures that the given identity is of a user who is a customer, as admins have no profile.
+func checkIsCustomer(identity *dto.Identity) *errs.AppError {
+	if identity.Role != domain.RoleUser || identity.CustomerId == "" {
+		logger.Error("Only users with a customer ID have a profile")
+		return errs.NewAuthorizationError("Access denied")
+	}
+	return nil
+}
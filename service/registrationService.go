@@ -1,9 +1,11 @@
 package service
 
 import (
+	"fmt"
 	"github.com/aliciatay-zls/banking-auth/domain"
 	"github.com/aliciatay-zls/banking-auth/dto"
 	"github.com/aliciatay-zls/banking-lib/errs"
+	"github.com/aliciatay-zls/banking-lib/logger"
 	"net/url"
 	"os"
 	"time"
@@ -11,31 +13,38 @@ import (
 
 type RegistrationService interface { //service (primary port)
 	Register(dto.RegistrationRequest) (*dto.RegistrationResponse, *errs.AppError)
-	CheckRegistration(string) (bool, *errs.AppError)
-	ResendLink(dto.ResendRequest) *errs.AppError
-	FinishRegistration(string) *errs.AppError
+	CheckRegistration(string) (string, *errs.AppError)
+	ResendLink(dto.ResendRequest) (*dto.RateLimitStatus, *errs.AppError)
+	ForceResendLink(dto.ForceResendRequest, string) *errs.AppError
+	FinishRegistration(string) (string, *errs.AppError)
+	GetRegistrationsPendingReview() ([]dto.PendingRegistrationResponse, *errs.AppError)
+	ReviewRegistration(dto.ReviewRegistrationRequest, string, bool) *errs.AppError
 }
 
 type DefaultRegistrationService struct { //business/domain object
 	registrationRepo domain.RegistrationRepository
-	emailRepo        domain.EmailRepository
 	tokenRepo        domain.TokenRepository
+	ottRepo          domain.OneTimeTokenRepository
+	suppressionRepo  domain.EmailSuppressionRepository
 }
 
-func NewRegistrationService(regRepo domain.RegistrationRepository, emailRepo domain.EmailRepository, tokenRepo domain.TokenRepository) DefaultRegistrationService {
-	return DefaultRegistrationService{regRepo, emailRepo, tokenRepo}
+func NewRegistrationService(regRepo domain.RegistrationRepository, tokenRepo domain.TokenRepository, ottRepo domain.OneTimeTokenRepository, suppressionRepo domain.EmailSuppressionRepository) DefaultRegistrationService {
+	return DefaultRegistrationService{regRepo, tokenRepo, ottRepo, suppressionRepo}
 }
 
 // Register uses the given dto.RegistrationRequest to check whether any of the following cases are true:
 //
 // 1) the given email was already used to register for an app account
 //
-// 2) there is already a User with the given username, or
+// 2) there is already a User with the given username,
 //
-// 3) there is already a Customer with the given email.
+// 3) there is already a Customer with the given email, or
 //
-// If so, the request is rejected. Otherwise, it is saved to the db, and a one-time use JWT is generated to form
-// a confirmation link which is then emailed to the requester.
+// 4) the onboarding option chosen, if any, does not exist.
+//
+// If so, the request is rejected. Otherwise, a one-time use JWT is generated to form a confirmation link, and the
+// request is saved to the db together with the email delivering the link to the requester, which is sent in the
+// background by the EmailOutboxService.
 func (s DefaultRegistrationService) Register(request dto.RegistrationRequest) (*dto.RegistrationResponse, *errs.AppError) {
 	if appErr := s.registrationRepo.IsEmailUsed(request.Email); appErr != nil {
 		return nil, appErr
@@ -43,6 +52,11 @@ func (s DefaultRegistrationService) Register(request dto.RegistrationRequest) (*
 	if appErr := s.registrationRepo.IsUsernameTaken(request.Username); appErr != nil {
 		return nil, appErr
 	}
+	if request.OnboardingOption != "" {
+		if appErr := s.registrationRepo.IsOnboardingOptionValid(request.OnboardingOption); appErr != nil {
+			return nil, appErr
+		}
+	}
 
 	hashedPw, appErr := domain.HashAndSaltPassword(request.Password)
 	if appErr != nil {
@@ -51,16 +65,12 @@ func (s DefaultRegistrationService) Register(request dto.RegistrationRequest) (*
 
 	registration := domain.NewRegistration(request, hashedPw)
 
-	timeEmailed, err := s.createAndSendLink(registration)
+	claims, outboxEmail, err := s.createLink(registration)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = s.registrationRepo.Save(registration); err != nil {
-		return nil, err
-	}
-
-	if err = s.registrationRepo.UpdateLastEmailedInfo(registration, timeEmailed); err != nil {
+	if err = s.registrationRepo.Save(registration, claims, *outboxEmail); err != nil {
 		return nil, err
 	}
 
@@ -81,55 +91,66 @@ func buildConfirmationURL(ott string) string {
 	return u.String()
 }
 
-// createAndSendLink creates a confirmation link based on the given registration and emails it, retrying every
-// domain.RetrySendEmailInterval seconds for a maximum of domain.RetrySendEmailAttempts times before giving up.
-func (s DefaultRegistrationService) createAndSendLink(reg domain.Registration) (string, *errs.AppError) {
-	ott, err := s.tokenRepo.BuildToken(reg.GetOneTimeTokenClaims())
+// createLink creates a confirmation link based on the given registration and the email delivering it through the
+// outbox. The claims of the one-time token in the link are returned to be saved along with the email.
+func (s DefaultRegistrationService) createLink(reg domain.Registration) (*domain.OneTimeTokenClaims, *domain.OutboxEmail, *errs.AppError) {
+	claims, err := reg.GetOneTimeTokenClaims()
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
-	link := buildConfirmationURL(ott)
-
-	timeEmailed, err := s.emailRepo.SendConfirmationEmail(reg.Email, link)
-	for i := 0; err != nil && i < domain.RetrySendEmailAttempts; i++ {
-		time.Sleep(domain.RetrySendEmailInterval)
-		timeEmailed, err = s.emailRepo.SendConfirmationEmail(reg.Email, link)
+	ott, err := s.tokenRepo.BuildToken(claims)
+	if err != nil {
+		return nil, nil, err
 	}
+
+	email, err := domain.NewOutboxEmail(reg.Email, reg.Locale, domain.EmailTemplateConfirmation,
+		map[string]any{"Link": buildConfirmationURL(ott)})
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 
-	return timeEmailed, nil
+	return claims, email, nil
 }
 
-// CheckRegistration uses the given token's claims to check that it is valid and to try retrieving an existing
-// Registration. The registration status is then returned.
-func (s DefaultRegistrationService) CheckRegistration(tokenString string) (bool, *errs.AppError) {
+// CheckRegistration uses the given token's claims to check that it is valid and has not been used or replaced by a
+// newer link, and to try retrieving an existing Registration. A message describing the registration status is then
+// returned, which is empty if the registration can go on to be confirmed.
+func (s DefaultRegistrationService) CheckRegistration(tokenString string) (string, *errs.AppError) {
 	c, err := s.tokenRepo.GetClaimsFromToken(tokenString, domain.TokenTypeOneTime)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 	claims := c.(*domain.OneTimeTokenClaims)
 	if appErr := claims.CheckExpiry(); appErr != nil {
-		return false, appErr
+		return "", appErr
+	}
+	if appErr := s.ottRepo.CheckUsable(claims.ID); appErr != nil {
+		return "", appErr
 	}
 
 	registration, err := s.registrationRepo.FindFromEmail(claims.Email)
 	if err != nil {
-		return false, err
+		return "", err
+	}
+	if err = registration.CheckExpiry(); err != nil {
+		return "", err
 	}
 
-	return registration.IsConfirmed(), nil
+	return registration.GetStatusMessage(), nil
 }
 
 // ResendLink retrieves the recipient's email from the token claims if needed, tries to retrieve an existing
 // Registration from the email and checks if resending the confirmation link to this email is allowed before doing so.
-func (s DefaultRegistrationService) ResendLink(request dto.ResendRequest) *errs.AppError {
+// Links are not resent to emails that bounced or complained, as they would not be delivered either.
+// The token given may be expired, used or invalidated as it is only used to identify the email, and sending the new
+// link invalidates all older ones. The state of the limits on resending is returned if the link was resent or if they
+// did not allow it.
+func (s DefaultRegistrationService) ResendLink(request dto.ResendRequest) (*dto.RateLimitStatus, *errs.AppError) {
 	var email string
 	if request.Type == dto.ResendRequestTypeUsingToken {
 		c, err := s.tokenRepo.GetClaimsFromToken(request.TokenString, domain.TokenTypeOneTime)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		claims := c.(*domain.OneTimeTokenClaims) //no need to check expiry
 		email = claims.Email
@@ -138,54 +159,136 @@ func (s DefaultRegistrationService) ResendLink(request dto.ResendRequest) *errs.
 	}
 
 	registration, err := s.registrationRepo.FindFromEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = registration.CheckExpiry(); err != nil {
+		return nil, err
+	}
+	status, err := registration.CanResendEmail()
+	if err != nil {
+		return status, err
+	}
+	if err = s.checkNotSuppressed(registration.Email); err != nil {
+		return nil, err
+	}
+
+	claims, outboxEmail, err := s.createLink(*registration)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.registrationRepo.EnqueueConfirmationLink(*registration, claims, *outboxEmail); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ForceResendLink resends the confirmation link of the Registration made using the email given in the request on
+// behalf of the given admin, e.g. after the customer's mail server rejected earlier links. Unlike ResendLink, the
+// limits on resending are not checked, but the Registration must still need the link and the email must not be on the
+// suppression list. The admin and the reason given are recorded for auditing.
+func (s DefaultRegistrationService) ForceResendLink(request dto.ForceResendRequest, admin string) *errs.AppError {
+	registration, err := s.registrationRepo.FindFromEmail(request.Email)
 	if err != nil {
 		return err
 	}
 
-	if err = registration.CanResendEmail(); err != nil {
+	if err = registration.CheckExpiry(); err != nil {
+		return err
+	}
+	if err = registration.CanForceResendEmail(); err != nil {
+		return err
+	}
+	if err = s.checkNotSuppressed(registration.Email); err != nil {
 		return err
 	}
 
-	timeEmailed, err := s.createAndSendLink(*registration)
+	claims, outboxEmail, err := s.createLink(*registration)
 	if err != nil {
 		return err
 	}
 
-	if err = s.registrationRepo.UpdateLastEmailedInfo(*registration, timeEmailed); err != nil {
+	if err = s.registrationRepo.ForceEnqueueConfirmationLink(*registration, claims, *outboxEmail, admin, request.Reason); err != nil {
 		return err
 	}
+	logger.Info(fmt.Sprintf("Confirmation link force resent to %s by %s", registration.Email, admin))
+	return nil
+}
 
+// checkNotSuppressed ensures that the given email is not on the suppression list, as emails to it would not be
+// delivered.
+func (s DefaultRegistrationService) checkNotSuppressed(email string) *errs.AppError {
+	isSuppressed, err := s.suppressionRepo.IsSuppressed(email)
+	if err != nil {
+		return err
+	}
+	if isSuppressed {
+		logger.Error("Cannot resend email as the email is on the suppression list")
+		return errs.NewValidationError("Email address cannot receive emails")
+	}
 	return nil
 }
 
-// FinishRegistration uses the given token's claims to double-check that it is valid, try retrieving an existing
-// Registration, initializing the new user in the db, and filling the remaining fields of the Registration which is
-// then used to update the db.
-func (s DefaultRegistrationService) FinishRegistration(tokenString string) *errs.AppError {
+// FinishRegistration uses the given token's claims to double-check that it is valid, before confirming the existing
+// Registration: in one db transaction, the token is used up so that the link cannot be replayed, the new user is
+// initialized in the db and the Registration is updated. If new registrations need to be approved by an admin, the
+// Registration is submitted for review instead of initializing the new user.
+//
+// Repeated requests for a Registration that was already confirmed or submitted do not change anything. A message
+// describing the registration status is returned, which is empty if the registration was just confirmed.
+func (s DefaultRegistrationService) FinishRegistration(tokenString string) (string, *errs.AppError) {
 	c, err := s.tokenRepo.GetClaimsFromToken(tokenString, domain.TokenTypeOneTime)
 	if err != nil {
-		return err
+		return "", err
 	}
 	claims := c.(*domain.OneTimeTokenClaims)
 	if appErr := claims.CheckExpiry(); appErr != nil {
-		return appErr
+		return "", appErr
 	}
 
-	registration, err := s.registrationRepo.FindFromEmail(claims.Email)
+	var registration *domain.Registration
+	var isRepeated bool
+	verifyTime := time.Now().UTC().Format(domain.FormatDateTime)
+	if domain.IsRegistrationApprovalRequired() {
+		registration, isRepeated, err = s.registrationRepo.SubmitForReview(claims.Email, claims.ID, verifyTime)
+	} else {
+		registration, isRepeated, err = s.registrationRepo.Confirm(claims.Email, claims.ID, verifyTime)
+	}
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	if registration.IsConfirmed() && !isRepeated {
+		return "", nil
 	}
+	return registration.GetStatusMessage(), nil
+}
 
-	createTime := time.Now().UTC().Format(domain.FormatDateTime)
-	customerId, err := s.registrationRepo.CreateNecessaryAccounts(registration, createTime)
+// GetRegistrationsPendingReview retrieves all registrations waiting to be approved or rejected by an admin.
+func (s DefaultRegistrationService) GetRegistrationsPendingReview() ([]dto.PendingRegistrationResponse, *errs.AppError) {
+	registrations, err := s.registrationRepo.FindPendingReview()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	completedRegistration := registration.Confirm(customerId, createTime)
-	if err = s.registrationRepo.Update(completedRegistration); err != nil {
-		return err
+	response := make([]dto.PendingRegistrationResponse, 0)
+	for _, r := range registrations {
+		response = append(response, r.ToPendingReviewDTO())
 	}
+	return response, nil
+}
 
-	return nil
+// ReviewRegistration approves or rejects the registration pending review given in the request, on behalf of the
+// given reviewer. On approval, the new user is initialized in the db. Either way, the email informing the applicant
+// of the decision is added to the outbox along with it.
+func (s DefaultRegistrationService) ReviewRegistration(request dto.ReviewRegistrationRequest, reviewer string, isApproved bool) *errs.AppError {
+	reviewTime := time.Now().UTC().Format(domain.FormatDateTime)
+	if isApproved {
+		_, err := s.registrationRepo.Approve(request.Email, reviewer, request.Reason, reviewTime)
+		return err
+	}
+	_, err := s.registrationRepo.Reject(request.Email, reviewer, request.Reason, reviewTime)
+	return err
 }
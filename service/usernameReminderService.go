@@ -0,0 +1,53 @@
+package service
+
+import (
+	"github.com/aliciatay-zls/banking-auth/domain"
+	"github.com/aliciatay-zls/banking-auth/dto"
+	"github.com/aliciatay-zls/banking-lib/errs"
+	"time"
+)
+
+type UsernameReminderService interface { //service (primary port)
+	ForgotUsername(dto.ForgotUsernameRequest) *errs.AppError
+}
+
+type DefaultUsernameReminderService struct { //business/domain object
+	reminderRepo    domain.UsernameReminderRepository
+	suppressionRepo domain.EmailSuppressionRepository
+}
+
+func NewDefaultUsernameReminderService(reminderRepo domain.UsernameReminderRepository, suppressionRepo domain.EmailSuppressionRepository) DefaultUsernameReminderService {
+	return DefaultUsernameReminderService{reminderRepo, suppressionRepo}
+}
+
+// ForgotUsername checks that another username reminder can be sent to the email in the given
+// dto.ForgotUsernameRequest, using the same limits as resending confirmation links, and records the attempt. If the
+// email belongs to a customer and is not on the suppression list, the email with their username is added to the
+// outbox along with the attempt. Either way, the response is the same so that it does not reveal whether the email is
+// registered.
+func (s DefaultUsernameReminderService) ForgotUsername(request dto.ForgotUsernameRequest) *errs.AppError {
+	reminder, err := s.reminderRepo.Find(request.Email)
+	if err != nil {
+		return err
+	}
+	if err = reminder.CanSend(); err != nil {
+		return err
+	}
+
+	username, locale, err := s.reminderRepo.FindUsername(request.Email)
+	if err != nil {
+		return err
+	}
+	isSuppressed, err := s.suppressionRepo.IsSuppressed(request.Email)
+	if err != nil {
+		return err
+	}
+	var email *domain.OutboxEmail
+	if username != "" && !isSuppressed {
+		if email, err = domain.NewUsernameReminderEmail(request.Email, locale, username); err != nil {
+			return err
+		}
+	}
+
+	return s.reminderRepo.UpdateLastEmailedInfo(request.Email, time.Now().UTC().Format(domain.FormatDateTime), email)
+}